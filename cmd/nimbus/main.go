@@ -1,41 +1,115 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
+	. "github.com/GearTech0/nimbus/internal/credentials"
 	. "github.com/GearTech0/nimbus/internal/nimbus"
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
 )
 
+// Credential sources, in the order they are tried
+var providers = []Provider{
+	&FileProvider{Path: "secret/keychain.json"},
+	&EnvProvider{Prefix: "NIMBUS_"},
+	&AppSettingProvider{Prefix: "Raindrop", LocalSettings: "local.settings.json"},
+}
+
+// Set once the credentials are known; see printError
+var redact = func(s string) string { return s }
+
+// Every error is printed through here, so no secret reaches the logs
+func printError(s string) {
+	fmt.Println("error: ", redact(s))
+}
+
+func printAccount(user *UserProfileType, stats *UserStatsResponseType) {
+	fmt.Printf("account %d (pro: %t)\n", user.Id, user.Pro)
+	for _, c := range stats.Items {
+		fmt.Printf("  collection %d: %d raindrops\n", c.Id, c.Count)
+	}
+	fmt.Printf("  broken: %d, duplicates: %d\n", stats.Meta.Broken.Count, stats.Meta.Duplicates.Count)
+}
+
 type InvokeResponse struct {
 	Outputs     map[string]interface{}
 	ReturnValue interface{}
 	Logs        []string
 }
-type KeyChain struct {
-	Bearer string `json:"bearer"`
-}
 
-func run(w http.ResponseWriter, r *http.Request) {
-	url := "https://api.raindrop.io/rest/v1/"
+func run(w http.ResponseWriter, r *http.Request) error {
+	creds, provider, err := Resolve(providers...)
+	if err != nil {
+		return err
+	}
+	redact = creds.Redact
+	logf := func(format string, args ...any) {
+		printError(fmt.Sprintf(format, args...))
+	}
+
+	var save func(*Credentials) error
+	if saver, ok := provider.(Saver); ok {
+		save = saver.Save
+	}
+
+	parent := context.Background()
+	if r != nil {
+		parent = r.Context()
+	}
+	ctx, cancel := InvocationContext(parent)
+	defer cancel()
 
-	// Bearer token from secret folder
-	contents, err := os.ReadFile("secret/keychain.json")
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	nimbus, err := SetupNimbus(
+		WithTokenSource(creds.TokenSource(save, logf)),
+		WithLogger(logger),
+	)
+	if err != nil {
+		return err
+	}
+	user, err := nimbus.Authenticate(ctx)
 	if err != nil {
-		fmt.Println("error: ", err)
+		return err
+	}
+	if stats, err := nimbus.AccountStats(ctx); err != nil {
+		logf("%s", err)
+	} else {
+		printAccount(user, stats)
 	}
 
-	var kc KeyChain
-	err = json.Unmarshal(contents, &kc)
+	if err := nimbus.LoadConfig(ctx); err != nil {
+		return err
+	}
+
+	resurfaced, err := nimbus.Resurface(ctx)
 	if err != nil {
-		fmt.Println("err: ", err)
+		logf("%s", err)
 	}
-	bearer := "Bearer " + kc.Bearer
+	fmt.Printf("resurfaced %d raindrops\n", len(resurfaced))
 
-	nimbus := SetupNimbus(url, bearer)
-	nimbus.RunExample()
+	queued, err := nimbus.Schedule(ctx)
+	if err != nil {
+		logf("%s", err)
+	}
+	fmt.Printf("queued %d raindrops\n", len(queued))
+
+	results, err := nimbus.ApplyRules(ctx)
+	if err != nil {
+		logf("%s", err)
+	}
+	for _, result := range results {
+		fmt.Printf("rule %q: matched %d, modified %d, skipped %d\n", result.Name, result.Matched, result.Modified, result.Skipped)
+	}
+
+	nimbus.State.LastRun = time.Now().UTC().Format(time.RFC3339)
+	if err := nimbus.SaveState(ctx); err != nil {
+		logf("%s", err)
+	}
 
 	// TODO: See if there's a cleaner way to handle this.
 	// response := &InvokeResponse{
@@ -46,10 +120,14 @@ func run(w http.ResponseWriter, r *http.Request) {
 	// }
 	// w.Header().Set("Content-Type", "application/json")
 	// _ = json.NewEncoder(w).Encode(response)
+	return nil
 }
 
 func main() {
-	run(nil, nil)
+	if err := run(nil, nil); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
 	// listenAddr := ":8080"
 	// if val, ok := os.LookupEnv("FUNCTIONS_CUSTOMHANDLER_PORT"); ok {
 	// 	listenAddr = ":" + val
@@ -1,3 +1,5 @@
 module github.com/GearTech0/nimbus
 
 go 1.22.5
+
+require gopkg.in/yaml.v3 v3.0.1
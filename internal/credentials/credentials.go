@@ -0,0 +1,117 @@
+// Package credentials resolves the Raindrop.io credentials Nimbus runs
+// with from the keychain file, environment variables or Azure Functions
+// app settings.
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+const REDACTED = "[REDACTED]"
+
+// Returned by a provider that has nothing configured
+var ErrNotFound = errors.New("credentials: not found")
+
+type Credentials struct {
+	Bearer string `json:"bearer,omitempty"`
+
+	// OAuth app credentials; when set, Token is refreshed as it expires
+	ClientId     string     `json:"client_id,omitempty"`
+	ClientSecret string     `json:"client_secret,omitempty"`
+	Token        *TokenType `json:"token,omitempty"`
+}
+
+// Never print secrets, even with %v or %+v
+func (c Credentials) String() string {
+	return fmt.Sprintf("Credentials{ClientId: %q, OAuth: %t}", c.ClientId, c.usesOAuth())
+}
+
+func (c *Credentials) usesOAuth() bool {
+	return c.ClientId != "" && c.Token != nil && c.Token.RefreshToken != ""
+}
+
+func (c *Credentials) empty() bool {
+	return c.Bearer == "" && !c.usesOAuth()
+}
+
+// Build the token source requests are authorized with. Refreshed tokens
+// are handed to save, when not nil, so they survive the next run. Without
+// a save, a rotated refresh token is lost when the run ends; that is
+// reported through logf, since the stored one may no longer work.
+func (c *Credentials) TokenSource(save func(*Credentials) error, logf func(format string, args ...any)) TokenSource {
+	if !c.usesOAuth() {
+		return StaticToken(c.Bearer)
+	}
+
+	config := &OAuthConfig{ClientId: c.ClientId, ClientSecret: c.ClientSecret}
+	stored := c.Token.RefreshToken
+	return config.TokenSource(c.Token, func(token *TokenType) {
+		c.Token = token
+		if save == nil {
+			if token.RefreshToken != stored && logf != nil {
+				logf("the refresh token was rotated and cannot be persisted; re-authorize and update the stored refresh token before the next run")
+			}
+			return
+		}
+		if err := save(c); err != nil && logf != nil {
+			logf("could not persist refreshed token: %s", c.Redact(err.Error()))
+		}
+	})
+}
+
+// Replace every secret in s, for anything that ends up in a log
+func (c *Credentials) Redact(s string) string {
+	secrets := []string{c.Bearer, c.ClientSecret}
+	if c.Token != nil {
+		secrets = append(secrets, c.Token.AccessToken, c.Token.RefreshToken)
+	}
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, REDACTED)
+		}
+	}
+	return s
+}
+
+// ------------------------------------------------------------------------
+// Providers
+// ------------------------------------------------------------------------
+
+type Provider interface {
+	// Where the provider looks, for error messages
+	Describe() string
+	// ErrNotFound when nothing is configured
+	Resolve() (*Credentials, error)
+}
+
+// Providers that can write refreshed tokens back. Only FileProvider
+// does; see Credentials.TokenSource for the others.
+type Saver interface {
+	Save(c *Credentials) error
+}
+
+// Try each provider in order and use the first that has credentials.
+// Fails when none do, naming every place that was searched.
+func Resolve(providers ...Provider) (*Credentials, Provider, error) {
+	var tried []string
+	for _, p := range providers {
+		c, err := p.Resolve()
+		if errors.Is(err, ErrNotFound) {
+			tried = append(tried, p.Describe())
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("credentials: %s: %w", p.Describe(), err)
+		}
+		if c.empty() {
+			tried = append(tried, p.Describe())
+			continue
+		}
+		return c, p, nil
+	}
+	return nil, nil, fmt.Errorf("%w (tried %s)", ErrNotFound, strings.Join(tried, ", "))
+}
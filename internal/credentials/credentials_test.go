@@ -0,0 +1,155 @@
+package credentials_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/GearTech0/nimbus/internal/credentials"
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+// A provider returning fixed credentials or a fixed error
+type staticProvider struct {
+	name  string
+	creds *Credentials
+	err   error
+}
+
+func (p *staticProvider) Describe() string {
+	return p.name
+}
+
+func (p *staticProvider) Resolve() (*Credentials, error) {
+	return p.creds, p.err
+}
+
+func TestResolveOrder(t *testing.T) {
+	missing := &staticProvider{name: "missing", err: ErrNotFound}
+	empty := &staticProvider{name: "empty", creds: &Credentials{}}
+	first := &staticProvider{name: "first", creds: &Credentials{Bearer: "one"}}
+	second := &staticProvider{name: "second", creds: &Credentials{Bearer: "two"}}
+
+	creds, provider, err := Resolve(missing, empty, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider != first || creds.Bearer != "one" {
+		t.Errorf("resolved %s from %s, want the first provider with credentials", creds, provider.Describe())
+	}
+
+	broken := &staticProvider{name: "broken", err: errors.New("permission denied")}
+	if _, _, err := Resolve(broken, first); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want the provider's own error", err)
+	}
+}
+
+func TestResolveNotFound(t *testing.T) {
+	_, _, err := Resolve(
+		&staticProvider{name: "file secret/keychain.json", err: ErrNotFound},
+		&staticProvider{name: "env NIMBUS_*", creds: &Credentials{ClientId: "no token"}},
+	)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+	for _, place := range []string{"file secret/keychain.json", "env NIMBUS_*"} {
+		if !strings.Contains(err.Error(), place) {
+			t.Errorf("%q does not name %s", err, place)
+		}
+	}
+}
+
+func TestAppSettingProvider(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "local.settings.json")
+	settings := `{"IsEncrypted": false, "Values": {
+		"RaindropBearer": "local-bearer",
+		"RaindropClientId": "app",
+		"RaindropClientSecret": "secret",
+		"RaindropRefreshToken": "refresh"
+	}}`
+	if err := os.WriteFile(local, []byte(settings), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// what Azure sets wins over the local file
+	t.Setenv("APPSETTING_RaindropBearer", "azure-bearer")
+
+	provider := &AppSettingProvider{Prefix: "Raindrop", LocalSettings: local}
+	creds, err := provider.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Bearer != "azure-bearer" || creds.ClientId != "app" || creds.ClientSecret != "secret" ||
+		creds.Token == nil || creds.Token.RefreshToken != "refresh" {
+		t.Errorf("unexpected credentials %+v", *creds)
+	}
+
+	missing := &AppSettingProvider{Prefix: "Other", LocalSettings: local}
+	if _, err := missing.Resolve(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	creds := &Credentials{
+		Bearer:       "bearer-secret",
+		ClientId:     "app",
+		ClientSecret: "client-secret",
+		Token:        &TokenType{AccessToken: "access-secret", RefreshToken: "refresh-secret"},
+	}
+
+	line := creds.Redact("GET user/ with bearer-secret, client-secret, access-secret and refresh-secret failed")
+	if line != "GET user/ with [REDACTED], [REDACTED], [REDACTED] and [REDACTED] failed" {
+		t.Errorf("not redacted: %q", line)
+	}
+	if printed := creds.String(); strings.Contains(printed, "secret") {
+		t.Errorf("String leaks a secret: %q", printed)
+	}
+}
+
+// Providers without a Saver cannot keep a rotated refresh token, which
+// must not go unnoticed
+func TestTokenSourceReportsLostRotation(t *testing.T) {
+	refreshed := "old-refresh"
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := fmt.Sprintf(`{"access_token": "access", "refresh_token": %q, "expires_in": 3600}`, refreshed)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+
+	for _, rotate := range []bool{false, true} {
+		if rotate {
+			refreshed = "new-refresh"
+		}
+		creds := &Credentials{ClientId: "app", ClientSecret: "secret", Token: &TokenType{RefreshToken: "old-refresh"}}
+		var logged []string
+		logf := func(format string, args ...any) { logged = append(logged, fmt.Sprintf(format, args...)) }
+
+		if _, err := creds.TokenSource(nil, logf).Token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if creds.Token.RefreshToken != refreshed {
+			t.Errorf("credentials hold refresh token %q, want %q", creds.Token.RefreshToken, refreshed)
+		}
+		if rotate != (len(logged) == 1) {
+			t.Errorf("rotated %t, logged %q", rotate, logged)
+		}
+		for _, line := range logged {
+			if strings.Contains(line, refreshed) {
+				t.Errorf("log leaks the refresh token: %q", line)
+			}
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,162 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+// ------------------------------------------------------------------------
+// File
+// ------------------------------------------------------------------------
+
+// Reads the keychain JSON file, e.g. secret/keychain.json
+type FileProvider struct {
+	Path string
+}
+
+func (p *FileProvider) Describe() string {
+	return "file " + p.Path
+}
+
+func (p *FileProvider) Resolve() (*Credentials, error) {
+	contents, err := os.ReadFile(p.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var c Credentials
+	if err := json.Unmarshal(contents, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (p *FileProvider) Save(c *Credentials) error {
+	contents, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.Path, contents, 0600)
+}
+
+// ------------------------------------------------------------------------
+// Environment
+// ------------------------------------------------------------------------
+
+// Reads <Prefix>BEARER, <Prefix>CLIENT_ID, <Prefix>CLIENT_SECRET and
+// <Prefix>REFRESH_TOKEN. Read only: a refresh token the API rotates
+// cannot be written back.
+type EnvProvider struct {
+	Prefix string
+}
+
+func (p *EnvProvider) Describe() string {
+	return "env " + p.Prefix + "*"
+}
+
+func (p *EnvProvider) Resolve() (*Credentials, error) {
+	return fromLookup(func(name string) string {
+		return os.Getenv(p.Prefix + name)
+	})
+}
+
+// ------------------------------------------------------------------------
+// Azure Functions app settings
+// ------------------------------------------------------------------------
+
+// Reads app settings named <Prefix>Bearer, <Prefix>ClientId,
+// <Prefix>ClientSecret and <Prefix>RefreshToken. In Azure these reach
+// the handler as environment variables (optionally APPSETTING_ prefixed);
+// locally they come from the Values of local.settings.json. Read only,
+// like EnvProvider: a rotated refresh token has to be copied into the
+// app setting by hand, or the next run fails to refresh.
+type AppSettingProvider struct {
+	Prefix        string
+	LocalSettings string
+}
+
+func (p *AppSettingProvider) Describe() string {
+	return "app settings " + p.Prefix + "*"
+}
+
+func (p *AppSettingProvider) Resolve() (*Credentials, error) {
+	local, err := p.readLocalSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	return fromLookup(func(name string) string {
+		key := p.Prefix + envToSetting(name)
+		if value := os.Getenv("APPSETTING_" + key); value != "" {
+			return value
+		}
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return local[key]
+	})
+}
+
+func (p *AppSettingProvider) readLocalSettings() (map[string]string, error) {
+	if p.LocalSettings == "" {
+		return nil, nil
+	}
+
+	contents, err := os.ReadFile(p.LocalSettings)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var settings struct {
+		Values map[string]string `json:"Values"`
+	}
+	if err := json.Unmarshal(contents, &settings); err != nil {
+		return nil, err
+	}
+	return settings.Values, nil
+}
+
+// BEARER -> Bearer, CLIENT_ID -> ClientId
+func envToSetting(name string) string {
+	out := []byte{}
+	upper := true
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if !upper && c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		upper = false
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+// Shared by the key/value providers
+func fromLookup(lookup func(name string) string) (*Credentials, error) {
+	c := &Credentials{
+		Bearer:       lookup("BEARER"),
+		ClientId:     lookup("CLIENT_ID"),
+		ClientSecret: lookup("CLIENT_SECRET"),
+	}
+	if refresh := lookup("REFRESH_TOKEN"); refresh != "" {
+		c.Token = &TokenType{RefreshToken: refresh}
+	}
+	if c.empty() {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
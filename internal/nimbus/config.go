@@ -0,0 +1,202 @@
+package nimbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+	"gopkg.in/yaml.v3"
+)
+
+// ------------------------------------------------------------------------
+// Configuration
+// ------------------------------------------------------------------------
+
+// The config lives in the note of the bot://nimbus.config raindrop, so it
+// can be edited from any Raindrop.io app. The excerpt is read when the
+// note is empty. Both JSON and YAML are accepted, optionally wrapped in a
+// ``` code block. Nimbus writes the note only when creating it; what it
+// needs to remember between runs goes to the bot://nimbus.state raindrop,
+// so formatting, comments and edits made during a run are never lost.
+type NimbusConfig struct {
+	// Days a raindrop has to sit unread before it is resurfaced
+	Retrospan int64 `json:"retrospan" yaml:"retrospan"`
+
+	Resurface ResurfaceConfig `json:"resurface" yaml:"resurface"`
+	Schedule  ScheduleConfig  `json:"schedule" yaml:"schedule"`
+	Rules     []RuleType      `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// Written back by Nimbus after every run, as JSON in the note of the
+// state raindrop
+type NimbusState struct {
+	// RFC 3339 time of the last completed run
+	LastRun string `json:"lastRun,omitempty" yaml:"lastRun,omitempty"`
+	// Day old raindrops were last resurfaced
+	LastResurfaced string `json:"lastResurfaced,omitempty" yaml:"lastResurfaced,omitempty"`
+	// Day the reading queue was last filled
+	LastScheduled string `json:"lastScheduled,omitempty" yaml:"lastScheduled,omitempty"`
+}
+
+// Config notes written before the state had its own raindrop carry it
+// under a state key; it is read once to seed the state raindrop
+type configNote struct {
+	NimbusConfig `yaml:",inline"`
+	State        *NimbusState `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+// Used for keys missing from the config raindrop
+func DefaultConfig() NimbusConfig {
+	return NimbusConfig{
+		Retrospan: 30,
+		// opt in by setting a count
+		Resurface: ResurfaceConfig{
+			Action: RESURFACE_IMPORTANT,
+		},
+		Schedule: ScheduleConfig{
+			PerDay:   3,
+			Policy:   SCHEDULE_OLDEST,
+			RemindAt: 8,
+		},
+	}
+}
+
+// Locate the config and state raindrops, creating them on the first run,
+// and read them into n.Config and n.State.
+func (n *Nimbus) LoadConfig(ctx context.Context) error {
+	note := configNote{NimbusConfig: DefaultConfig()}
+	defaults, err := json.MarshalIndent(note.NimbusConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	id, created, err := n.CreateRaindropOnce(ctx, RaindropType{
+		Title: NIMBUS_CONFIG_TITLE,
+		Link:  NIMBUS_CONFIG_LINK,
+		Note:  string(defaults),
+	})
+	if err != nil {
+		return fmt.Errorf("nimbus: locating config: %w", err)
+	}
+	n.configId = id
+
+	if !created {
+		res, err := n.Client.GetRaindropContext(ctx, int(id))
+		if err != nil {
+			return fmt.Errorf("nimbus: reading config: %w", err)
+		}
+		text := res.Item.Note
+		if strings.TrimSpace(text) == "" {
+			text = res.Item.Excerpt
+		}
+		if err := parseConfig(text, &note); err != nil {
+			return fmt.Errorf("nimbus: parsing config raindrop %d: %w", id, err)
+		}
+	}
+	n.Config = note.NimbusConfig
+
+	var state NimbusState
+	if note.State != nil {
+		state = *note.State
+	}
+	return n.loadState(ctx, state)
+}
+
+// Read the state raindrop into n.State, creating it from legacy when it
+// does not exist yet
+func (n *Nimbus) loadState(ctx context.Context, legacy NimbusState) error {
+	text, err := json.Marshal(legacy)
+	if err != nil {
+		return err
+	}
+	id, created, err := n.CreateRaindropOnce(ctx, RaindropType{
+		Title: NIMBUS_STATE_TITLE,
+		Link:  NIMBUS_STATE_LINK,
+		Note:  string(text),
+	})
+	if err != nil {
+		return fmt.Errorf("nimbus: locating state: %w", err)
+	}
+	n.stateId = id
+	if created {
+		n.State = legacy
+		return nil
+	}
+
+	res, err := n.Client.GetRaindropContext(ctx, int(id))
+	if err != nil {
+		return fmt.Errorf("nimbus: reading state: %w", err)
+	}
+	var state NimbusState
+	if note := strings.TrimSpace(res.Item.Note); note != "" {
+		if err := json.Unmarshal([]byte(note), &state); err != nil {
+			return fmt.Errorf("nimbus: parsing state raindrop %d: %w", id, err)
+		}
+	}
+	n.State = state
+	return nil
+}
+
+// Write n.State back to the state raindrop loaded by LoadConfig. The
+// config raindrop is left alone.
+func (n *Nimbus) SaveState(ctx context.Context) error {
+	if n.stateId == 0 {
+		return fmt.Errorf("nimbus: state was never loaded")
+	}
+
+	text, err := json.Marshal(n.State)
+	if err != nil {
+		return err
+	}
+	if _, err := n.Client.UpdateRaindropContext(ctx, int(n.stateId), RaindropType{Note: string(text)}); err != nil {
+		return fmt.Errorf("nimbus: saving state: %w", err)
+	}
+	return nil
+}
+
+// The config and state raindrops must never be touched by the jobs;
+// matching the links too covers runs where LoadConfig was not called
+func (n *Nimbus) isConfig(r RaindropType) bool {
+	if r.Id != 0 && (r.Id == n.configId || r.Id == n.stateId) {
+		return true
+	}
+	return r.Link == NIMBUS_CONFIG_LINK || r.Link == NIMBUS_STATE_LINK
+}
+
+// Decode text over the values already in note; blank text leaves note
+// alone
+func parseConfig(text string, note *configNote) error {
+	text = stripCodeFence(text)
+	if text == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(text, "{") {
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(note)
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	decoder.KnownFields(true)
+	return decoder.Decode(note)
+}
+
+// Notes are markdown, so a config may be pasted in as a code block
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+
+	// drop the opening line, which may name a language
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	} else {
+		return ""
+	}
+	text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,164 @@
+package nimbus
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+// ------------------------------------------------------------------------
+// Reading lifecycle
+// ------------------------------------------------------------------------
+
+// Where a raindrop is in the reading lifecycle. Each state but
+// STATE_NONE is carried as a NIMBUS_TAG_PREFIX tag; a raindrop holds at
+// most one of them.
+type LifecycleState string
+
+// Raindrops Nimbus has not touched yet
+const STATE_NONE LifecycleState = ""
+const STATE_INBOX LifecycleState = "inbox"
+const STATE_QUEUED LifecycleState = "queued"
+const STATE_READING LifecycleState = "reading"
+const STATE_DONE LifecycleState = "done"
+const STATE_ARCHIVED LifecycleState = "archived"
+
+const NIMBUS_TAG_INBOX = NIMBUS_TAG_PREFIX + string(STATE_INBOX)
+const NIMBUS_TAG_QUEUED = NIMBUS_TAG_PREFIX + string(STATE_QUEUED)
+const NIMBUS_TAG_READING = NIMBUS_TAG_PREFIX + string(STATE_READING)
+const NIMBUS_TAG_DONE = NIMBUS_TAG_PREFIX + string(STATE_DONE)
+const NIMBUS_TAG_ARCHIVED = NIMBUS_TAG_PREFIX + string(STATE_ARCHIVED)
+
+// Transitions are recorded as lines of the note starting with this
+/*
+	nimbus: queued 2024-05-01T08:00:00Z
+	nimbus: reading 2024-05-01T12:30:00Z
+*/
+const NIMBUS_HISTORY_PREFIX = "nimbus:"
+
+// The states each state may move on to
+var lifecycleTransitions = map[LifecycleState][]LifecycleState{
+	STATE_NONE:     {STATE_INBOX, STATE_QUEUED},
+	STATE_INBOX:    {STATE_QUEUED, STATE_DONE, STATE_ARCHIVED},
+	STATE_QUEUED:   {STATE_INBOX, STATE_READING, STATE_DONE},
+	STATE_READING:  {STATE_QUEUED, STATE_DONE},
+	STATE_DONE:     {STATE_QUEUED, STATE_ARCHIVED},
+	STATE_ARCHIVED: {STATE_INBOX},
+}
+
+type TransitionType struct {
+	State LifecycleState
+	At    time.Time
+}
+
+func (s LifecycleState) Tag() string {
+	if s == STATE_NONE {
+		return ""
+	}
+	return NIMBUS_TAG_PREFIX + string(s)
+}
+
+func (s LifecycleState) Valid() bool {
+	_, ok := lifecycleTransitions[s]
+	return ok
+}
+
+func CanTransition(from LifecycleState, to LifecycleState) bool {
+	return slices.Contains(lifecycleTransitions[from], to)
+}
+
+// Read the state of a raindrop from its tags. More than one state tag
+// is an error rather than a guess.
+func StateOf(r RaindropType) (LifecycleState, error) {
+	state := STATE_NONE
+	for _, tag := range r.Tags {
+		candidate, ok := stateOfTag(tag)
+		if !ok || candidate == state {
+			continue
+		}
+		if state != STATE_NONE {
+			return STATE_NONE, fmt.Errorf("nimbus: raindrop %d is both %s and %s", r.Id, state, candidate)
+		}
+		state = candidate
+	}
+	return state, nil
+}
+
+func stateOfTag(tag string) (LifecycleState, bool) {
+	state := LifecycleState(strings.TrimPrefix(tag, NIMBUS_TAG_PREFIX))
+	if state == STATE_NONE || state.Tag() != tag || !state.Valid() {
+		return STATE_NONE, false
+	}
+	return state, true
+}
+
+// The transitions recorded in a raindrop's note, oldest first
+func History(r RaindropType) []TransitionType {
+	var history []TransitionType
+	for _, line := range strings.Split(r.Note, "\n") {
+		fields := strings.Fields(strings.TrimPrefix(line, NIMBUS_HISTORY_PREFIX))
+		if !strings.HasPrefix(line, NIMBUS_HISTORY_PREFIX) || len(fields) != 2 {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			continue
+		}
+		history = append(history, TransitionType{State: LifecycleState(fields[0]), At: at})
+	}
+	return history
+}
+
+// Move a raindrop to another state, swapping its state tag and recording
+// the transition in its note. Returns the updated raindrop.
+func (n *Nimbus) Transition(ctx context.Context, r RaindropType, to LifecycleState) (*RaindropType, error) {
+	update, err := transitionUpdate(r, to, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := n.Client.UpdateRaindropContext(ctx, int(r.Id), update)
+	if err != nil {
+		return nil, fmt.Errorf("nimbus: moving raindrop %d to %s: %w", r.Id, to, err)
+	}
+	return &res.Item, nil
+}
+
+// Transition for when only the id is at hand
+func (n *Nimbus) TransitionById(ctx context.Context, id int64, to LifecycleState) (*RaindropType, error) {
+	res, err := n.Client.GetRaindropContext(ctx, int(id))
+	if err != nil {
+		return nil, err
+	}
+	return n.Transition(ctx, res.Item, to)
+}
+
+// The tags and note that move r to state to. Other tags are kept, so
+// the result can be extended before it is sent.
+func transitionUpdate(r RaindropType, to LifecycleState, now time.Time) (RaindropType, error) {
+	from, err := StateOf(r)
+	if err != nil {
+		return RaindropType{}, err
+	}
+	if !CanTransition(from, to) {
+		return RaindropType{}, fmt.Errorf("nimbus: raindrop %d cannot go from %q to %q", r.Id, from, to)
+	}
+
+	tags := slices.DeleteFunc(slices.Clone(r.Tags), func(tag string) bool {
+		_, ok := stateOfTag(tag)
+		return ok
+	})
+	tags = append(tags, to.Tag())
+
+	note := r.Note
+	if note != "" && !strings.HasSuffix(note, "\n") {
+		note += "\n"
+	}
+	note += fmt.Sprintf("%s %s %s", NIMBUS_HISTORY_PREFIX, to, now.Format(time.RFC3339))
+
+	return RaindropType{Tags: tags, Note: note}, nil
+}
@@ -1,8 +1,10 @@
 package nimbus
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"strings"
+	"time"
 
 	. "github.com/GearTech0/nimbus/pkg/raindropio"
 )
@@ -10,41 +12,127 @@ import (
 // --------------CONSTANTS-------------------
 const NIMBUS_CONFIG_TITLE = "nimbus.config"
 const NIMBUS_CONFIG_LINK = "bot://nimbus.config"
+const NIMBUS_STATE_TITLE = "nimbus.state"
+const NIMBUS_STATE_LINK = "bot://nimbus.state"
 const NIMBUS_TEST_COLLECTION = "46406303"
 const NIMBUS_TAG_PREFIX = "nmbs_"
 
+// Matches functionTimeout in host.json. Runs stop NIMBUS_SHUTDOWN_MARGIN
+// early so in-flight requests can wind down before the host kills us.
+const NIMBUS_FUNCTION_TIMEOUT = 5 * time.Minute
+const NIMBUS_SHUTDOWN_MARGIN = 15 * time.Second
+
 type Nimbus struct {
 	Config NimbusConfig `json:"config"`
+	State  NimbusState  `json:"state"`
 	Client *RaindropIOClient
+
+	// Where Config and State were loaded from, see LoadConfig
+	configId int64
+	stateId  int64
 }
 
 // Nimbus "singleton"
 var nimbus = &Nimbus{}
 
-type NimbusConfig struct {
-	Retrospan int64 `json:"retrospan"`
+// Build the singleton's client from opts, see raindropio.NewClient
+func SetupNimbus(opts ...ClientOption) (*Nimbus, error) {
+	client, err := NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	nimbus.Client = client
+	return nimbus, nil
 }
 
-func SetupNimbus(baseurl string, bearer string) *Nimbus {
-	nimbus.Client = &RaindropIOClient{}
-	nimbus.Client.Baseurl = baseurl
-	nimbus.Client.Bearer = bearer
-	nimbus.Client.Handle = &http.Client{}
-	return nimbus
+// Derive the context a run works under from the function invocation
+func InvocationContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, NIMBUS_FUNCTION_TIMEOUT-NIMBUS_SHUTDOWN_MARGIN)
 }
 
-func (n *Nimbus) RunExample() {
-	// Define new Raindrop
-	filter := RaindropUpdateType{
-		Ids:       []int{826815716, 823844493},
-		Important: true,
+// Check the bearer token against the API before doing any work
+func (n *Nimbus) Authenticate(ctx context.Context) (*UserProfileType, error) {
+	res, err := n.Client.GetUserContext(ctx)
+	if IsUnauthorized(err) {
+		return nil, fmt.Errorf("nimbus: bearer token rejected: %w", err)
+	}
+	if err != nil {
+		return nil, err
 	}
+	return &res.User, nil
+}
 
-	n.Config = NimbusConfig{}
+// Account level numbers for the run log: raindrops per system
+// collection, broken links and duplicates
+func (n *Nimbus) AccountStats(ctx context.Context) (*UserStatsResponseType, error) {
+	return n.Client.GetUserStatsContext(ctx)
+}
 
-	// Create test raindrop
-	opRes := n.Client.UpdateManyRaindrops(46406303, filter)
-	opRes.ExecuteOnResponse(func(jsonResponse string) {
-		fmt.Print(jsonResponse)
-	})
+// Create a raindrop unless its link is already saved. Returns the id of
+// the new or existing raindrop and whether it was created.
+func (n *Nimbus) CreateRaindropOnce(ctx context.Context, r RaindropType) (int64, bool, error) {
+	exists, err := n.Client.CheckURLsExistContext(ctx, []string{r.Link})
+	if err != nil {
+		return 0, false, err
+	}
+	if len(exists.Ids) > 0 {
+		return exists.Ids[0], false, nil
+	}
+
+	created, err := n.Client.CreateRaindropContext(ctx, r)
+	if err != nil {
+		return 0, false, err
+	}
+	return created.Item.Id, true, nil
+}
+
+// Find a collection by title, creating it at the root when there is
+// none. Root collections win over nested ones of the same name.
+func (n *Nimbus) EnsureCollection(ctx context.Context, title string) (int64, error) {
+	roots, err := n.Client.GetRootCollectionsContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	children, err := n.Client.GetChildCollectionsContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range append(roots.Items, children.Items...) {
+		if c.Title == title {
+			return c.Id, nil
+		}
+	}
+
+	created, err := n.Client.CreateCollectionContext(ctx, CollectionType{Title: title})
+	if err != nil {
+		return 0, err
+	}
+	return created.Item.Id, nil
+}
+
+// List the tags Nimbus owns, i.e. those carrying NIMBUS_TAG_PREFIX
+func (n *Nimbus) OwnTags(ctx context.Context) ([]TagType, error) {
+	res, err := n.Client.GetTagsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var own []TagType
+	for _, tag := range res.Items {
+		if strings.HasPrefix(tag.Id, NIMBUS_TAG_PREFIX) {
+			own = append(own, tag)
+		}
+	}
+	return own, nil
+}
+
+// Remove tags from Nimbus's namespace, refusing anything a user owns
+func (n *Nimbus) RemoveOwnTags(ctx context.Context, tags []string) error {
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, NIMBUS_TAG_PREFIX) {
+			return fmt.Errorf("nimbus: refusing to remove user tag %q", tag)
+		}
+	}
+	_, err := n.Client.RemoveTagsContext(ctx, 0, tags)
+	return err
 }
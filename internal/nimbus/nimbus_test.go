@@ -1 +1,404 @@
 package nimbus_test
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	. "github.com/GearTech0/nimbus/internal/nimbus"
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+	"github.com/GearTech0/nimbus/pkg/raindropio/raindropiotest"
+)
+
+func TestOwnTags(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	collection := srv.AddCollection(CollectionType{Title: "Inbox"})
+	srv.AddRaindrop(collection, RaindropType{Tags: []string{"go", NIMBUS_TAG_PREFIX + "queued"}})
+	n := &Nimbus{Client: srv.Client()}
+
+	tags, err := n.OwnTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Id != NIMBUS_TAG_PREFIX+"queued" {
+		t.Errorf("unexpected tags: %+v", tags)
+	}
+
+	if err := n.RemoveOwnTags(context.Background(), []string{"go"}); err == nil {
+		t.Error("removing a user tag should fail")
+	}
+}
+
+func TestCreateRaindropOnce(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	n := &Nimbus{Client: srv.Client()}
+	r := RaindropType{Title: "Nimbus", Link: "https://github.com/GearTech0/nimbus"}
+
+	var first int64
+	for i, want := range []bool{true, false} {
+		id, created, err := n.CreateRaindropOnce(context.Background(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created != want {
+			t.Errorf("call %d: created %t, want %t", i, created, want)
+		}
+		if i == 0 {
+			first = id
+		} else if id != first {
+			t.Errorf("call %d: got id %d, want existing %d", i, id, first)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	// the first run creates the config raindrop with the defaults
+	n := &Nimbus{Client: srv.Client()}
+	if err := n.LoadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n.Config, DefaultConfig()) {
+		t.Errorf("got %+v, want the defaults", n.Config)
+	}
+
+	// edited from the app as YAML in a code block
+	exists, err := n.Client.CheckURLsExist([]string{NIMBUS_CONFIG_LINK})
+	if err != nil || len(exists.Ids) != 1 {
+		t.Fatalf("config raindrop not created: %v", err)
+	}
+	id := exists.Ids[0]
+	note := "```yaml\nretrospan: 90\n```"
+	if _, err := n.Client.UpdateRaindrop(int(id), RaindropType{Note: note}); err != nil {
+		t.Fatal(err)
+	}
+
+	n = &Nimbus{Client: srv.Client()}
+	if err := n.LoadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n.Config.Retrospan != 90 {
+		t.Errorf("got retrospan %d, want 90", n.Config.Retrospan)
+	}
+
+	// a run only writes the state raindrop, so the note stays as typed
+	n.State.LastRun = "2024-05-01T00:00:00Z"
+	if err := n.SaveState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _, _ := srv.Raindrop(id); saved.Note != note {
+		t.Errorf("config note rewritten: %q", saved.Note)
+	}
+	n = &Nimbus{Client: srv.Client()}
+	if err := n.LoadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n.State.LastRun != "2024-05-01T00:00:00Z" {
+		t.Errorf("state not kept between runs: %+v", n.State)
+	}
+
+	// unknown keys are typos, not something to silently ignore
+	n.Client.UpdateRaindrop(int(id), RaindropType{Note: `{"retrospam": 7}`})
+	if err := n.LoadConfig(ctx); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+// Notes from before the state raindrop seed it once
+func TestLoadConfigLegacyState(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+
+	note := "retrospan: 45\nstate:\n  lastScheduled: \"2024-05-01\"\n"
+	srv.AddRaindrop(int64(COLLECTION_UNSORTED), RaindropType{Title: NIMBUS_CONFIG_TITLE, Link: NIMBUS_CONFIG_LINK, Note: note})
+
+	n := &Nimbus{Client: srv.Client()}
+	if err := n.LoadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n.Config.Retrospan != 45 || n.State.LastScheduled != "2024-05-01" {
+		t.Errorf("got config %+v and state %+v", n.Config, n.State)
+	}
+
+	// from now on the state raindrop wins over the note
+	n.State.LastScheduled = "2024-05-02"
+	if err := n.SaveState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	n = &Nimbus{Client: srv.Client()}
+	if err := n.LoadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n.State.LastScheduled != "2024-05-02" {
+		t.Errorf("got state %+v", n.State)
+	}
+}
+
+func TestResurface(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	collection := srv.AddCollection(CollectionType{Title: "Reading"})
+	old := "2020-01-01T00:00:00Z"
+	oldest := srv.AddRaindrop(collection, RaindropType{Title: "oldest", Created: "2019-01-01T00:00:00Z", Tags: []string{"go"}})
+	read := srv.AddRaindrop(collection, RaindropType{Title: "read", Created: old, Tags: []string{NIMBUS_TAG_DONE}})
+	unread := srv.AddRaindrop(collection, RaindropType{Title: "unread", Created: old})
+	srv.AddRaindrop(collection, RaindropType{Title: "recent"})
+
+	n := &Nimbus{Client: srv.Client(), Config: DefaultConfig()}
+	n.Config.Resurface = ResurfaceConfig{Count: 1, Action: RESURFACE_MOVE}
+
+	if ids, _ := (&Nimbus{Client: srv.Client(), Config: DefaultConfig()}).Resurface(context.Background()); len(ids) != 0 {
+		t.Errorf("resurfaced %v without opting in", ids)
+	}
+
+	// oldest first, once a day, and never the same raindrop twice
+	for _, want := range []int64{oldest, unread} {
+		n.State.LastResurfaced = ""
+		ids, err := n.Resurface(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 1 || ids[0] != want {
+			t.Fatalf("resurfaced %v, want [%d]", ids, want)
+		}
+		if ids, _ := n.Resurface(context.Background()); len(ids) != 0 {
+			t.Fatalf("resurfaced %v twice in a day", ids)
+		}
+	}
+	n.State.LastResurfaced = ""
+	if ids, err := n.Resurface(context.Background()); err != nil || len(ids) != 0 {
+		t.Errorf("resurfaced %v (%v), want nothing left", ids, err)
+	}
+
+	r, in, _ := srv.Raindrop(oldest)
+	if in == collection || !slices.Equal(r.Tags, []string{"go", NIMBUS_TAG_RESURFACED}) {
+		t.Errorf("oldest not moved and tagged: collection %d, tags %v", in, r.Tags)
+	}
+	if _, in, _ := srv.Raindrop(read); in != collection {
+		t.Error("a read raindrop was resurfaced")
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	backlog := srv.AddCollection(CollectionType{Title: "Backlog"})
+	add := func(domain string, created string) int64 {
+		return srv.AddRaindrop(backlog, RaindropType{Domain: domain, Link: "https://" + domain + "/" + created, Created: created})
+	}
+	a1 := add("a.com", "2024-01-01T00:00:00Z")
+	a2 := add("a.com", "2024-01-02T00:00:00Z")
+	b := add("b.com", "2024-01-03T00:00:00Z")
+	c := add("c.com", "2024-01-04T00:00:00Z")
+	// finished before Nimbus ever scheduled it
+	srv.AddRaindrop(backlog, RaindropType{Domain: "d.com", Link: "https://d.com", Created: "2023-01-01T00:00:00Z", Tags: []string{NIMBUS_TAG_DONE}})
+
+	n := &Nimbus{Client: srv.Client(), Config: DefaultConfig()}
+	n.Config.Schedule = ScheduleConfig{Backlog: backlog, PerDay: 3, Policy: SCHEDULE_DOMAINS, RemindAt: 9}
+
+	ids, err := n.Schedule(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int64{a1, b, c}) {
+		t.Errorf("queued %v, want one per domain %v", ids, []int64{a1, b, c})
+	}
+	r, _, _ := srv.Raindrop(b)
+	if !slices.Contains(r.Tags, NIMBUS_TAG_QUEUED) || r.Reminder == nil || !strings.HasSuffix(r.Reminder.Date, "T09:00:00Z") {
+		t.Errorf("not queued with a reminder: %+v", r)
+	}
+
+	// once per day
+	if ids, _ := n.Schedule(context.Background()); len(ids) != 0 {
+		t.Errorf("queued %v twice in a day", ids)
+	}
+
+	// the next day only what was never queued is left
+	n.State.LastScheduled = ""
+	ids, err = n.Schedule(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int64{a2}) {
+		t.Errorf("queued %v, want [%d] and nothing already done", ids, a2)
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	inbox := srv.AddCollection(CollectionType{Title: "Inbox"})
+	videos := srv.AddCollection(CollectionType{Title: "Videos"})
+	old := "2020-01-01T00:00:00Z"
+	talk := srv.AddRaindrop(inbox, RaindropType{Title: "GopherCon talk", Domain: "m.youtube.com", Type: TYPE_VIDEO, Created: old})
+	recent := srv.AddRaindrop(inbox, RaindropType{Title: "New video", Domain: "youtube.com", Type: TYPE_VIDEO})
+	read := srv.AddRaindrop(inbox, RaindropType{Title: "Old post", Tags: []string{"later"}, Created: old})
+	sponsored := srv.AddRaindrop(inbox, RaindropType{Title: "[Sponsored] Buy now", Created: old})
+
+	n := &Nimbus{Client: srv.Client(), Config: DefaultConfig()}
+	n.Config.Rules = []RuleType{
+		{Name: "park old videos", When: ConditionType{Domain: "youtube.com", OlderThan: 30}, Then: ActionType{Tag: []string{"watch"}, Move: videos}},
+		{Name: "drop later", When: ConditionType{Collection: inbox, Tags: []string{"later"}}, Then: ActionType{Untag: []string{"later"}, RemindIn: 7}},
+		{Name: "no ads", When: ConditionType{Title: `^\[Sponsored\]`}, Then: ActionType{Delete: true}},
+	}
+
+	results, err := n.ApplyRules(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int64{1, 1, 1} {
+		if results[i].Matched != int(want) || results[i].Modified != want {
+			t.Errorf("rule %q: matched %d, modified %d, want %d", results[i].Name, results[i].Matched, results[i].Modified, want)
+		}
+	}
+
+	if r, in, _ := srv.Raindrop(talk); in != videos || !slices.Equal(r.Tags, []string{"watch"}) {
+		t.Errorf("old video not parked: collection %d, tags %v", in, r.Tags)
+	}
+	if _, in, _ := srv.Raindrop(recent); in != inbox {
+		t.Error("a recent video was parked")
+	}
+	if r, _, _ := srv.Raindrop(read); len(r.Tags) != 0 || r.Reminder == nil {
+		t.Errorf("tag not removed or no reminder: %+v", r)
+	}
+	if _, in, _ := srv.Raindrop(sponsored); in != int64(COLLECTION_TRASH) {
+		t.Errorf("sponsored raindrop in collection %d, want the trash", in)
+	}
+
+	n.Config.Rules = []RuleType{{Name: "everything", Then: ActionType{Delete: true}}}
+	if _, err := n.ApplyRules(context.Background()); err == nil {
+		t.Error("a rule without conditions should be refused")
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	collection := srv.AddCollection(CollectionType{Title: "Reading"})
+	id := srv.AddRaindrop(collection, RaindropType{Title: "Go memory model", Tags: []string{"go"}, Note: "worth a reread"})
+	n := &Nimbus{Client: srv.Client()}
+	ctx := context.Background()
+
+	for _, to := range []LifecycleState{STATE_INBOX, STATE_QUEUED, STATE_READING, STATE_DONE} {
+		r, err := n.TransitionById(ctx, id, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state, err := StateOf(*r); err != nil || state != to {
+			t.Fatalf("got state %q (%v), want %q", state, err, to)
+		}
+	}
+	if _, err := n.TransitionById(ctx, id, STATE_READING); err == nil {
+		t.Error("done to reading should be refused")
+	}
+
+	r, _, _ := srv.Raindrop(id)
+	if !slices.Equal(r.Tags, []string{"go", NIMBUS_TAG_DONE}) {
+		t.Errorf("state tags not swapped: %v", r.Tags)
+	}
+	if !strings.HasPrefix(r.Note, "worth a reread\n") {
+		t.Errorf("user note lost: %q", r.Note)
+	}
+	history := History(r)
+	if len(history) != 4 || history[0].State != STATE_INBOX || history[3].State != STATE_DONE {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	if _, err := StateOf(RaindropType{Tags: []string{NIMBUS_TAG_QUEUED, NIMBUS_TAG_DONE}}); err == nil {
+		t.Error("two state tags should be an error")
+	}
+}
+
+func TestApplyRulesTransitions(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	collection := srv.AddCollection(CollectionType{Title: "Reading"})
+	done := srv.AddRaindrop(collection, RaindropType{Title: "done", Tags: []string{NIMBUS_TAG_DONE}, Created: "2020-01-01T00:00:00Z"})
+	reading := srv.AddRaindrop(collection, RaindropType{Title: "reading", Tags: []string{NIMBUS_TAG_READING}, Created: "2020-01-01T00:00:00Z"})
+
+	n := &Nimbus{Client: srv.Client(), Config: DefaultConfig()}
+	n.Config.Rules = []RuleType{
+		{Name: "archive", When: ConditionType{State: STATE_DONE, OlderThan: 30}, Then: ActionType{State: STATE_ARCHIVED}},
+	}
+	results, err := n.ApplyRules(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Matched != 1 || results[0].Modified != 1 {
+		t.Errorf("matched %d, modified %d, want 1 and 1", results[0].Matched, results[0].Modified)
+	}
+	if r, _, _ := srv.Raindrop(done); !slices.Equal(r.Tags, []string{NIMBUS_TAG_ARCHIVED}) || len(History(r)) != 1 {
+		t.Errorf("not archived: %+v", r)
+	}
+	if r, _, _ := srv.Raindrop(reading); !slices.Equal(r.Tags, []string{NIMBUS_TAG_READING}) {
+		t.Errorf("reading raindrop changed: %v", r.Tags)
+	}
+
+	// archived raindrops cannot be marked done
+	n.Config.Rules = []RuleType{{Name: "finish", When: ConditionType{Collection: collection}, Then: ActionType{State: STATE_DONE}}}
+	results, err = n.ApplyRules(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Matched != 2 || results[0].Modified != 1 || results[0].Skipped != 1 {
+		t.Errorf("matched %d, modified %d, skipped %d, want 2, 1 and 1", results[0].Matched, results[0].Modified, results[0].Skipped)
+	}
+
+	n.Config.Rules = []RuleType{{Name: "collide", When: ConditionType{Domain: "go.dev"}, Then: ActionType{Tag: []string{NIMBUS_TAG_DONE}}}}
+	if _, err := n.ApplyRules(context.Background()); err == nil {
+		t.Error("tagging with a state tag should be refused")
+	}
+}
+
+func TestJobsSkipConfigRaindrop(t *testing.T) {
+	srv := raindropiotest.NewServer()
+	defer srv.Close()
+
+	unsorted := int64(COLLECTION_UNSORTED)
+	config := srv.AddRaindrop(unsorted, RaindropType{Title: NIMBUS_CONFIG_TITLE, Link: NIMBUS_CONFIG_LINK, Created: "2020-01-01T00:00:00Z"})
+
+	n := &Nimbus{Client: srv.Client()}
+	if err := n.LoadConfig(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	n.Config.Resurface = ResurfaceConfig{Count: 5, Action: RESURFACE_MOVE}
+	n.Config.Schedule = ScheduleConfig{Backlog: unsorted, PerDay: 5, Policy: SCHEDULE_OLDEST}
+	n.Config.Rules = []RuleType{{Name: "clean unsorted", When: ConditionType{Collection: unsorted}, Then: ActionType{Delete: true}}}
+
+	if ids, err := n.Resurface(context.Background()); err != nil || len(ids) != 0 {
+		t.Errorf("resurfaced %v (%v)", ids, err)
+	}
+	if ids, err := n.Schedule(context.Background()); err != nil || len(ids) != 0 {
+		t.Errorf("queued %v (%v)", ids, err)
+	}
+	if results, err := n.ApplyRules(context.Background()); err != nil || results[0].Matched != 0 {
+		t.Errorf("rule matched %+v (%v)", results, err)
+	}
+
+	r, in, _ := srv.Raindrop(config)
+	if in != unsorted || len(r.Tags) != 0 || r.Note != "" {
+		t.Errorf("config raindrop changed: collection %d, %+v", in, r)
+	}
+	exists, err := n.Client.CheckURLsExist([]string{NIMBUS_STATE_LINK})
+	if err != nil || len(exists.Ids) != 1 {
+		t.Fatalf("state raindrop not created: %v", err)
+	}
+	if r, in, _ := srv.Raindrop(exists.Ids[0]); in != unsorted || len(r.Tags) != 0 {
+		t.Errorf("state raindrop changed: collection %d, %+v", in, r)
+	}
+}
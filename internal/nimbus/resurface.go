@@ -0,0 +1,120 @@
+package nimbus
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+// ------------------------------------------------------------------------
+// Resurfacing
+// ------------------------------------------------------------------------
+
+// Marks raindrops that were resurfaced once, so they are not picked again
+const NIMBUS_TAG_RESURFACED = NIMBUS_TAG_PREFIX + "resurfaced"
+
+// How a resurfaced raindrop is brought back to the user's attention
+const RESURFACE_IMPORTANT = "important"
+const RESURFACE_REMIND = "remind"
+const RESURFACE_MOVE = "move"
+
+const RESURFACE_COLLECTION = "Resurfaced"
+
+type ResurfaceConfig struct {
+	// Raindrops resurfaced per run; 0 turns resurfacing off
+	Count int `json:"count" yaml:"count"`
+	// One of the RESURFACE_ actions
+	Action string `json:"action" yaml:"action"`
+	// Collection to look in; 0 looks everywhere but the trash
+	Collection int64 `json:"collection,omitempty" yaml:"collection,omitempty"`
+	// Title of the collection RESURFACE_MOVE moves into; created when
+	// missing
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+}
+
+// Bring back up to Config.Resurface.Count unread raindrops, i.e. not
+// done or archived, saved more than Config.Retrospan days ago, oldest
+// first. Runs at most once a day. Returns the ids of the raindrops
+// resurfaced.
+func (n *Nimbus) Resurface(ctx context.Context) ([]int64, error) {
+	config := n.Config.Resurface
+	if config.Count <= 0 || n.Config.Retrospan <= 0 {
+		return nil, nil
+	}
+	switch config.Action {
+	case RESURFACE_IMPORTANT, RESURFACE_REMIND, RESURFACE_MOVE:
+	default:
+		return nil, fmt.Errorf("nimbus: unknown resurface action %q", config.Action)
+	}
+
+	now := time.Now().UTC()
+	today := now.Format(SEARCH_DATE_LAYOUT)
+	if n.State.LastResurfaced == today {
+		return nil, nil
+	}
+
+	search := NewSearch().
+		CreatedBefore(now.AddDate(0, 0, -int(n.Config.Retrospan))).
+		WithoutTag(NIMBUS_TAG_DONE).
+		WithoutTag(NIMBUS_TAG_ARCHIVED).
+		WithoutTag(NIMBUS_TAG_RESURFACED)
+	if config.Action == RESURFACE_IMPORTANT {
+		search.Important(false)
+	}
+
+	pager := n.Client.NewRaindropPager(int(config.Collection), FilterType{
+		Search:  search.String(),
+		Sort:    "created",
+		PerPage: config.Count,
+	})
+	var picked []RaindropType
+	for len(picked) < config.Count && pager.Next(ctx) {
+		if r := pager.Raindrop(); !n.isConfig(r) {
+			picked = append(picked, r)
+		}
+	}
+	if err := pager.Err(); err != nil {
+		return nil, err
+	}
+	if len(picked) == 0 {
+		n.State.LastResurfaced = today
+		return nil, nil
+	}
+
+	var target int64
+	if config.Action == RESURFACE_MOVE {
+		title := config.Target
+		if title == "" {
+			title = RESURFACE_COLLECTION
+		}
+		var err error
+		if target, err = n.EnsureCollection(ctx, title); err != nil {
+			return nil, err
+		}
+	}
+
+	var resurfaced []int64
+	for _, r := range picked {
+		// tags are replaced as a whole on update
+		update := RaindropType{Tags: append(slices.Clone(r.Tags), NIMBUS_TAG_RESURFACED)}
+		switch config.Action {
+		case RESURFACE_IMPORTANT:
+			update.Important = true
+		case RESURFACE_REMIND:
+			update.Reminder = &ReminderType{Date: now.Format(time.RFC3339)}
+		case RESURFACE_MOVE:
+			update.Collection = &CollectionParentType{Id: target}
+		}
+
+		if _, err := n.Client.UpdateRaindropContext(ctx, int(r.Id), update); err != nil {
+			return resurfaced, fmt.Errorf("nimbus: resurfacing raindrop %d: %w", r.Id, err)
+		}
+		resurfaced = append(resurfaced, r.Id)
+	}
+
+	n.State.LastResurfaced = today
+	return resurfaced, nil
+}
@@ -0,0 +1,321 @@
+package nimbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+// ------------------------------------------------------------------------
+// Rules
+// ------------------------------------------------------------------------
+
+// An automation from the config: every raindrop matching When gets Then
+// applied. Rules run in order, each seeing the changes of the ones
+// before it.
+/*
+	rules:
+	  - name: park old videos
+	    when: {type: video, olderThan: 60}
+	    then: {tag: [later], move: 1234}
+*/
+type RuleType struct {
+	Name string        `json:"name" yaml:"name"`
+	When ConditionType `json:"when" yaml:"when"`
+	Then ActionType    `json:"then" yaml:"then"`
+}
+
+// Every field set must hold for a raindrop to match
+type ConditionType struct {
+	// 0 looks everywhere but the trash
+	Collection  int64    `json:"collection,omitempty" yaml:"collection,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	WithoutTags []string `json:"withoutTags,omitempty" yaml:"withoutTags,omitempty"`
+	// Also matches subdomains
+	Domain string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	// One of the raindropio TYPE_ constants
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Days since the raindrop was saved
+	OlderThan int64 `json:"olderThan,omitempty" yaml:"olderThan,omitempty"`
+	// Regular expression matched against the title
+	Title     string         `json:"title,omitempty" yaml:"title,omitempty"`
+	Important *bool          `json:"important,omitempty" yaml:"important,omitempty"`
+	State     LifecycleState `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+type ActionType struct {
+	Tag       []string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Untag     []string `json:"untag,omitempty" yaml:"untag,omitempty"`
+	Move      int64    `json:"move,omitempty" yaml:"move,omitempty"`
+	Important bool     `json:"important,omitempty" yaml:"important,omitempty"`
+	// Days from now to set a reminder for
+	RemindIn int64 `json:"remindIn,omitempty" yaml:"remindIn,omitempty"`
+	// Raindrops whose state cannot move on to this one are left alone
+	State LifecycleState `json:"state,omitempty" yaml:"state,omitempty"`
+	// Move to the trash; cannot be combined with other actions
+	Delete bool `json:"delete,omitempty" yaml:"delete,omitempty"`
+}
+
+type RuleResultType struct {
+	Name     string
+	Matched  int
+	Modified int64
+	// Matched raindrops left alone because their state cannot move on to
+	// Then.State, or has more than one state tag
+	Skipped int
+	Err     error
+}
+
+// A rule checked and ready to run
+type compiledRule struct {
+	RuleType
+	title *regexp.Regexp
+}
+
+func compileRule(rule RuleType) (*compiledRule, error) {
+	compiled := &compiledRule{RuleType: rule}
+	when, then := rule.When, rule.Then
+
+	if when.Collection == 0 && len(when.Tags) == 0 && len(when.WithoutTags) == 0 && when.Domain == "" &&
+		when.Type == "" && when.OlderThan == 0 && when.Title == "" && when.Important == nil && when.State == STATE_NONE {
+		return nil, fmt.Errorf("nimbus: rule %q has no conditions", rule.Name)
+	}
+	if when.Title != "" {
+		title, err := regexp.Compile(when.Title)
+		if err != nil {
+			return nil, fmt.Errorf("nimbus: rule %q: %w", rule.Name, err)
+		}
+		compiled.title = title
+	}
+
+	if !when.State.Valid() || !then.State.Valid() {
+		return nil, fmt.Errorf("nimbus: rule %q names an unknown state", rule.Name)
+	}
+	// states only change through transitions, so they never collide
+	for _, tag := range append(slices.Clone(then.Tag), then.Untag...) {
+		if _, ok := stateOfTag(tag); ok {
+			return nil, fmt.Errorf("nimbus: rule %q changes the state tag %q, use state instead", rule.Name, tag)
+		}
+	}
+
+	others := len(then.Tag) > 0 || len(then.Untag) > 0 || then.Move != 0 || then.Important || then.RemindIn > 0 || then.State != STATE_NONE
+	if then.Delete && others {
+		return nil, fmt.Errorf("nimbus: rule %q combines delete with other actions", rule.Name)
+	}
+	if !then.Delete && !others {
+		return nil, fmt.Errorf("nimbus: rule %q has no actions", rule.Name)
+	}
+	return compiled, nil
+}
+
+// Narrow the candidates server side; matches has the final say
+func (rule *compiledRule) search(now time.Time) string {
+	when := rule.When
+	search := NewSearch()
+	for _, tag := range when.Tags {
+		search.Tag(tag)
+	}
+	for _, tag := range when.WithoutTags {
+		search.WithoutTag(tag)
+	}
+	if when.Type != "" {
+		search.Type(when.Type)
+	}
+	if when.Important != nil {
+		search.Important(*when.Important)
+	}
+	if when.State != STATE_NONE {
+		search.Tag(when.State.Tag())
+	}
+	if when.OlderThan > 0 {
+		// the operator only has day precision, so include the cutoff day
+		search.CreatedBefore(now.AddDate(0, 0, -int(when.OlderThan)+1))
+	}
+	return search.String()
+}
+
+func (rule *compiledRule) matches(r RaindropType, now time.Time) bool {
+	when := rule.When
+	if when.Collection != 0 && r.CollectionId() != when.Collection {
+		return false
+	}
+	for _, tag := range when.Tags {
+		if !slices.Contains(r.Tags, tag) {
+			return false
+		}
+	}
+	for _, tag := range when.WithoutTags {
+		if slices.Contains(r.Tags, tag) {
+			return false
+		}
+	}
+	if when.Domain != "" && r.Domain != when.Domain && !strings.HasSuffix(r.Domain, "."+when.Domain) {
+		return false
+	}
+	if when.Type != "" && r.Type != when.Type {
+		return false
+	}
+	if when.Important != nil && r.Important != *when.Important {
+		return false
+	}
+	if when.OlderThan > 0 {
+		created, err := time.Parse(time.RFC3339, r.Created)
+		if err != nil || now.Sub(created) < time.Duration(when.OlderThan)*24*time.Hour {
+			return false
+		}
+	}
+	if rule.title != nil && !rule.title.MatchString(r.Title) {
+		return false
+	}
+	if when.State != STATE_NONE {
+		if state, err := StateOf(r); err != nil || state != when.State {
+			return false
+		}
+	}
+	return true
+}
+
+// Untagging, reminders and transitions cannot be done in bulk
+func (rule *compiledRule) perItem() bool {
+	return len(rule.Then.Untag) > 0 || rule.Then.RemindIn > 0 || rule.Then.State != STATE_NONE
+}
+
+// Run every rule of the config. A failing rule does not stop the ones
+// after it; its error is in its result and joined into the returned one.
+func (n *Nimbus) ApplyRules(ctx context.Context) ([]RuleResultType, error) {
+	var rules []*compiledRule
+	for _, rule := range n.Config.Rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, compiled)
+	}
+
+	now := time.Now().UTC()
+	var results []RuleResultType
+	var errs []error
+	for _, rule := range rules {
+		result := n.applyRule(ctx, rule, now)
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("nimbus: rule %q: %w", rule.Name, result.Err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+func (n *Nimbus) applyRule(ctx context.Context, rule *compiledRule, now time.Time) RuleResultType {
+	result := RuleResultType{Name: rule.Name}
+
+	candidates, err := n.Client.GetAllRaindropsContext(ctx, int(rule.When.Collection), FilterType{Search: rule.search(now)})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	// bulk endpoints work on one collection at a time
+	var collections []int64
+	byCollection := map[int64][]RaindropType{}
+	for _, r := range candidates {
+		if n.isConfig(r) || !rule.matches(r, now) {
+			continue
+		}
+		result.Matched++
+		if _, ok := byCollection[r.CollectionId()]; !ok {
+			collections = append(collections, r.CollectionId())
+		}
+		byCollection[r.CollectionId()] = append(byCollection[r.CollectionId()], r)
+	}
+
+	for _, collection := range collections {
+		modified, skipped, err := n.applyActions(ctx, rule, int(collection), byCollection[collection], now)
+		result.Modified += modified
+		result.Skipped += skipped
+		if err != nil {
+			result.Err = err
+			break
+		}
+	}
+	return result
+}
+
+func (n *Nimbus) applyActions(ctx context.Context, rule *compiledRule, collection int, matched []RaindropType, now time.Time) (int64, int, error) {
+	then := rule.Then
+	ids := make([]int, len(matched))
+	for i, r := range matched {
+		ids[i] = int(r.Id)
+	}
+
+	if then.Delete {
+		res, err := n.Client.RemoveManyRaindropsContext(ctx, collection, SelectionType{Ids: ids})
+		if err != nil {
+			return 0, 0, err
+		}
+		return res.Modified, 0, nil
+	}
+
+	if !rule.perItem() {
+		// tags sent in bulk are added to the existing ones
+		updates := RaindropUpdateType{Ids: ids, Important: then.Important, Tags: then.Tag}
+		if then.Move != 0 {
+			updates.Collection = &CollectionParentType{Id: then.Move}
+		}
+		res, err := n.Client.UpdateManyRaindropsContext(ctx, collection, updates)
+		if err != nil {
+			return 0, 0, err
+		}
+		return res.Modified, 0, nil
+	}
+
+	var modified int64
+	var skipped int
+	for _, r := range matched {
+		// tags sent on a single update replace the existing ones
+		tags := slices.DeleteFunc(append([]string{}, r.Tags...), func(tag string) bool {
+			return slices.Contains(then.Untag, tag)
+		})
+		for _, tag := range then.Tag {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+
+		// a patch, so an emptied tag list is still sent
+		update := RaindropPatchType{Tags: &tags}
+		if then.State != STATE_NONE {
+			from, err := StateOf(r)
+			if err != nil || !CanTransition(from, then.State) {
+				skipped++
+				continue
+			}
+			r.Tags = tags
+			transition, err := transitionUpdate(r, then.State, now)
+			if err != nil {
+				return modified, skipped, err
+			}
+			update.Tags = &transition.Tags
+			update.Note = &transition.Note
+		}
+		if then.Important {
+			update.Important = &then.Important
+		}
+		if then.Move != 0 {
+			update.Collection = &CollectionParentType{Id: then.Move}
+		}
+		if then.RemindIn > 0 {
+			update.Reminder = &ReminderType{Date: now.AddDate(0, 0, int(then.RemindIn)).Format(time.RFC3339)}
+		}
+		if _, err := n.Client.PatchRaindropContext(ctx, int(r.Id), update); err != nil {
+			return modified, skipped, err
+		}
+		modified++
+	}
+	return modified, skipped, nil
+}
@@ -0,0 +1,170 @@
+package nimbus
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+// ------------------------------------------------------------------------
+// Scheduling
+// ------------------------------------------------------------------------
+
+// Kept on every raindrop that was ever queued, so it is never picked again
+const NIMBUS_TAG_SCHEDULED = NIMBUS_TAG_PREFIX + "scheduled"
+
+// Scheduling policies
+const SCHEDULE_OLDEST = "oldest"
+const SCHEDULE_RANDOM = "random"
+const SCHEDULE_SHORTEST = "shortest"
+const SCHEDULE_DOMAINS = "domains"
+
+// Picks up to count of the candidates, which come sorted oldest first
+type SchedulePolicy func(candidates []RaindropType, count int) []RaindropType
+
+var schedulePolicies = map[string]SchedulePolicy{
+	SCHEDULE_OLDEST:   scheduleOldest,
+	SCHEDULE_RANDOM:   scheduleRandom,
+	SCHEDULE_SHORTEST: scheduleShortest,
+	SCHEDULE_DOMAINS:  scheduleDomains,
+}
+
+type ScheduleConfig struct {
+	// Collection articles are drawn from; 0 turns the scheduler off
+	Backlog int64 `json:"backlog" yaml:"backlog"`
+	// Articles queued per day
+	PerDay int `json:"perDay" yaml:"perDay"`
+	// One of the SCHEDULE_ policies
+	Policy string `json:"policy" yaml:"policy"`
+	// Hour of the day, in UTC, reminders are set for
+	RemindAt int `json:"remindAt" yaml:"remindAt"`
+}
+
+// Queue today's articles from the backlog, unless that already happened
+// today. Only untracked and inbox raindrops are picked; they move to
+// STATE_QUEUED and get a reminder. Returns the ids of the raindrops
+// queued.
+func (n *Nimbus) Schedule(ctx context.Context) ([]int64, error) {
+	config := n.Config.Schedule
+	if config.Backlog == 0 || config.PerDay <= 0 {
+		return nil, nil
+	}
+	policy, ok := schedulePolicies[config.Policy]
+	if !ok {
+		return nil, fmt.Errorf("nimbus: unknown schedule policy %q", config.Policy)
+	}
+	if config.RemindAt < 0 || config.RemindAt > 23 {
+		return nil, fmt.Errorf("nimbus: remindAt %d is not an hour of the day", config.RemindAt)
+	}
+
+	now := time.Now().UTC()
+	today := now.Format(SEARCH_DATE_LAYOUT)
+	if n.State.LastScheduled == today {
+		return nil, nil
+	}
+
+	candidates, err := n.Client.GetAllRaindropsContext(ctx, int(config.Backlog), FilterType{
+		Search: NewSearch().WithoutTag(NIMBUS_TAG_SCHEDULED).String(),
+		Sort:   "created",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates = slices.DeleteFunc(candidates, func(r RaindropType) bool {
+		if n.isConfig(r) {
+			return true
+		}
+		// done, archived or already in progress raindrops are not backlog
+		state, err := StateOf(r)
+		return err != nil || (state != STATE_NONE && state != STATE_INBOX)
+	})
+
+	remind := time.Date(now.Year(), now.Month(), now.Day(), config.RemindAt, 0, 0, 0, time.UTC)
+	var queued []int64
+	for _, r := range policy(candidates, config.PerDay) {
+		update, err := transitionUpdate(r, STATE_QUEUED, now)
+		if err != nil {
+			return queued, err
+		}
+		update.Tags = append(update.Tags, NIMBUS_TAG_SCHEDULED)
+		update.Reminder = &ReminderType{Date: remind.Format(time.RFC3339)}
+
+		if _, err := n.Client.UpdateRaindropContext(ctx, int(r.Id), update); err != nil {
+			return queued, fmt.Errorf("nimbus: scheduling raindrop %d: %w", r.Id, err)
+		}
+		queued = append(queued, r.Id)
+	}
+
+	n.State.LastScheduled = today
+	return queued, nil
+}
+
+func scheduleOldest(candidates []RaindropType, count int) []RaindropType {
+	return candidates[:min(count, len(candidates))]
+}
+
+func scheduleRandom(candidates []RaindropType, count int) []RaindropType {
+	shuffled := slices.Clone(candidates)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled[:min(count, len(shuffled))]
+}
+
+// The API has no reading time, so the size of the permanent copy stands
+// in for it. Raindrops without one go last.
+func scheduleShortest(candidates []RaindropType, count int) []RaindropType {
+	size := func(r RaindropType) int64 {
+		if r.Cache != nil && r.Cache.Size > 0 {
+			return r.Cache.Size
+		}
+		if r.File != nil && r.File.Size > 0 {
+			return r.File.Size
+		}
+		return -1
+	}
+
+	sorted := slices.Clone(candidates)
+	slices.SortStableFunc(sorted, func(a, b RaindropType) int {
+		sa, sb := size(a), size(b)
+		if (sa < 0) != (sb < 0) {
+			// known sizes first
+			return cmp.Compare(sb, sa)
+		}
+		return cmp.Compare(sa, sb)
+	})
+	return sorted[:min(count, len(sorted))]
+}
+
+// Take the oldest of each domain in turn, domains ordered by their
+// oldest raindrop
+func scheduleDomains(candidates []RaindropType, count int) []RaindropType {
+	var domains []string
+	byDomain := map[string][]RaindropType{}
+	for _, r := range candidates {
+		if _, ok := byDomain[r.Domain]; !ok {
+			domains = append(domains, r.Domain)
+		}
+		byDomain[r.Domain] = append(byDomain[r.Domain], r)
+	}
+
+	var picked []RaindropType
+	for len(picked) < min(count, len(candidates)) {
+		for _, domain := range domains {
+			if len(picked) == count {
+				break
+			}
+			if queue := byDomain[domain]; len(queue) > 0 {
+				picked = append(picked, queue[0])
+				byDomain[domain] = queue[1:]
+			}
+		}
+	}
+	return picked
+}
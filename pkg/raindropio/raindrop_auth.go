@@ -0,0 +1,232 @@
+package raindropio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------------------
+// Authorization
+// ------------------------------------------------------------------------
+
+const OAUTH_AUTHORIZE_URL string = "https://raindrop.io/oauth/authorize"
+const OAUTH_TOKEN_URL string = "https://raindrop.io/oauth/access_token"
+
+// Tokens are refreshed this long before they expire
+const TOKEN_EXPIRY_DELTA = time.Minute
+
+var ErrNoRefreshToken = errors.New("raindropio: token expired and has no refresh token")
+
+type TokenType struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// A token is usable when it has an access token that is not about to
+// expire. Tokens without an expiry never expire.
+func (t *TokenType) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(TOKEN_EXPIRY_DELTA).Before(t.Expiry)
+}
+
+// Keep tokens out of logs, even when printed with %v or %+v
+func (t TokenType) String() string {
+	return fmt.Sprintf("TokenType{AccessToken: [REDACTED], Expiry: %s}", t.Expiry.Format(time.RFC3339))
+}
+
+// Value of the Authorization header
+func (t *TokenType) Header() string {
+	return "Bearer " + t.AccessToken
+}
+
+// Supplies the token each request is authorized with
+type TokenSource interface {
+	Token(ctx context.Context) (*TokenType, error)
+}
+
+// Sources that can drop a token before it expires, e.g. once the API
+// rejected it because it was rotated or revoked. The next Token call
+// then fetches a fresh one.
+type InvalidatingTokenSource interface {
+	TokenSource
+	Invalidate(token *TokenType)
+}
+
+type staticTokenSource struct {
+	token *TokenType
+}
+
+// A source that always hands out the same token, e.g. a test token from
+// the Raindrop.io app settings.
+func StaticToken(accessToken string) TokenSource {
+	return &staticTokenSource{token: &TokenType{AccessToken: accessToken, TokenType: "Bearer"}}
+}
+
+func (s *staticTokenSource) Token(ctx context.Context) (*TokenType, error) {
+	return s.token, nil
+}
+
+// ------------------------------------------------------------------------
+// OAuth2 code flow
+// ------------------------------------------------------------------------
+
+type OAuthConfig struct {
+	ClientId     string
+	ClientSecret string
+	RedirectURI  string
+
+	// Default to OAUTH_AUTHORIZE_URL, OAUTH_TOKEN_URL and http.DefaultClient
+	AuthURL  string
+	TokenURL string
+	Handle   *http.Client
+}
+
+type tokenRequestBody struct {
+	GrantType    string `json:"grant_type"`
+	Code         string `json:"code,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RedirectURI  string `json:"redirect_uri,omitempty"`
+}
+
+// The url to send the user to so they can grant access
+func (c *OAuthConfig) AuthCodeURL(state string) string {
+	authURL := c.AuthURL
+	if authURL == "" {
+		authURL = OAUTH_AUTHORIZE_URL
+	}
+
+	q := url.Values{}
+	q.Set("client_id", c.ClientId)
+	q.Set("redirect_uri", c.RedirectURI)
+	if state != "" {
+		q.Set("state", state)
+	}
+	return authURL + "?" + q.Encode()
+}
+
+// Trade the code from the redirect for a token
+func (c *OAuthConfig) Exchange(ctx context.Context, code string) (*TokenType, error) {
+	return c.requestToken(ctx, tokenRequestBody{
+		GrantType:    "authorization_code",
+		Code:         code,
+		ClientId:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+		RedirectURI:  c.RedirectURI,
+	})
+}
+
+// Get a fresh access token. The response may rotate the refresh token,
+// so persist the whole result.
+func (c *OAuthConfig) Refresh(ctx context.Context, refreshToken string) (*TokenType, error) {
+	token, err := c.requestToken(ctx, tokenRequestBody{
+		GrantType:    "refresh_token",
+		RefreshToken: refreshToken,
+		ClientId:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (c *OAuthConfig) requestToken(ctx context.Context, in tokenRequestBody) (*TokenType, error) {
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = OAUTH_TOKEN_URL
+	}
+	handle := c.Handle
+	if handle == nil {
+		handle = http.DefaultClient
+	}
+
+	parsed, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, bytes.NewReader(parsed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	opRes := OperationResponseType{method: "POST", route: tokenURL}
+	opRes.response, opRes.err = handle.Do(req)
+	token, err := decodeResponse[TokenType](opRes)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// A source that starts from token and refreshes it shortly before it
+// expires. onRefresh, when not nil, is called with every new token so
+// it can be persisted; it runs with the source locked.
+func (c *OAuthConfig) TokenSource(token *TokenType, onRefresh func(*TokenType)) TokenSource {
+	return &refreshingTokenSource{config: c, token: token, onRefresh: onRefresh}
+}
+
+type refreshingTokenSource struct {
+	config    *OAuthConfig
+	onRefresh func(*TokenType)
+
+	mu    sync.Mutex
+	token *TokenType
+	// Set when the API rejected token before it expired
+	stale bool
+}
+
+func (s *refreshingTokenSource) Token(ctx context.Context) (*TokenType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() && !s.stale {
+		return s.token, nil
+	}
+	if s.token == nil || s.token.RefreshToken == "" {
+		return nil, ErrNoRefreshToken
+	}
+
+	token, err := s.config.Refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	s.stale = false
+	if s.onRefresh != nil {
+		s.onRefresh(token)
+	}
+	return token, nil
+}
+
+// Only the token handed out last can be invalidated, so a late 401 for
+// an old token does not throw away a fresh one
+func (s *refreshingTokenSource) Invalidate(token *TokenType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token != nil && s.token != nil && token.AccessToken == s.token.AccessToken {
+		s.stale = true
+	}
+}
@@ -0,0 +1,165 @@
+package raindropio
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// ------------------------------------------------------------------------
+// Bulk operations
+// ------------------------------------------------------------------------
+
+var ErrEmptySelection = errors.New("raindropio: selection needs ids or a search")
+var ErrAmbiguousSelection = errors.New("raindropio: selection takes ids or a search, not both")
+
+// Picks the raindrops of a collection a bulk operation applies to, either
+// by id or by search query. With DryRun set nothing is changed; the
+// operation only lists what it would have affected.
+type SelectionType struct {
+	Ids    []int
+	Search string
+	DryRun bool
+}
+
+type BulkResultType struct {
+	ModifiedResponseType
+	DryRun bool
+	// Only filled on dry runs
+	Affected []RaindropType
+}
+
+func (sel *SelectionType) validate() error {
+	if len(sel.Ids) == 0 && sel.Search == "" {
+		return ErrEmptySelection
+	}
+	if len(sel.Ids) > 0 && sel.Search != "" {
+		return ErrAmbiguousSelection
+	}
+	return nil
+}
+
+// The selection's search travels in the query string
+func (sel *SelectionType) route(collectionId int) string {
+	route := ROUTE_RAINDROPS + strconv.Itoa(collectionId)
+	if sel.Search != "" {
+		route += "?search=" + url.QueryEscape(sel.Search)
+	}
+	return route
+}
+
+// Remove many raindrops. Removed raindrops go to the trash; removing
+// from the trash deletes them permanently.
+/*
+	[IN] form:
+		ids []int
+
+	[OUT] form:
+		result bool
+		modified int
+*/
+func (n *RaindropIOClient) RemoveManyRaindrops(collectionId int, sel SelectionType) (*BulkResultType, error) {
+	return n.RemoveManyRaindropsContext(context.Background(), collectionId, sel)
+}
+
+// RemoveManyRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveManyRaindropsContext(ctx context.Context, collectionId int, sel SelectionType) (*BulkResultType, error) {
+	if err := sel.validate(); err != nil {
+		return nil, err
+	}
+	if sel.DryRun {
+		return n.previewSelection(ctx, collectionId, sel)
+	}
+
+	// a search goes without a body, so there is no null id list for the
+	// API to misread
+	var in any
+	if len(sel.Ids) > 0 {
+		in = IDList{Ids: sel.Ids}
+	}
+	res, err := decodeResponse[ModifiedResponseType](n.ExecuteContext(ctx, "DELETE", sel.route(collectionId), in))
+	if err != nil {
+		return nil, err
+	}
+	return &BulkResultType{ModifiedResponseType: *res}, nil
+}
+
+// Update many raindrops picked by a selection. updates.Ids is ignored.
+/*
+	[IN] form:
+		ids []int
+		important bool
+		tags []string
+		media []string
+		cover string
+		collection CollectionParentType
+
+	[OUT] form:
+		result bool
+		modified int
+*/
+func (n *RaindropIOClient) UpdateSelectedRaindrops(collectionId int, sel SelectionType, updates RaindropUpdateType) (*BulkResultType, error) {
+	return n.UpdateSelectedRaindropsContext(context.Background(), collectionId, sel, updates)
+}
+
+// UpdateSelectedRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UpdateSelectedRaindropsContext(ctx context.Context, collectionId int, sel SelectionType, updates RaindropUpdateType) (*BulkResultType, error) {
+	if err := sel.validate(); err != nil {
+		return nil, err
+	}
+	if sel.DryRun {
+		return n.previewSelection(ctx, collectionId, sel)
+	}
+
+	updates.Ids = sel.Ids
+	res, err := decodeResponse[ModifiedResponseType](n.ExecuteContext(ctx, "PUT", sel.route(collectionId), updates))
+	if err != nil {
+		return nil, err
+	}
+	return &BulkResultType{ModifiedResponseType: *res}, nil
+}
+
+// Move many raindrops into another collection
+func (n *RaindropIOClient) MoveRaindrops(collectionId int, sel SelectionType, to int) (*BulkResultType, error) {
+	return n.MoveRaindropsContext(context.Background(), collectionId, sel, to)
+}
+
+// MoveRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) MoveRaindropsContext(ctx context.Context, collectionId int, sel SelectionType, to int) (*BulkResultType, error) {
+	updates := RaindropUpdateType{Collection: &CollectionParentType{Id: int64(to)}}
+	return n.UpdateSelectedRaindropsContext(ctx, collectionId, sel, updates)
+}
+
+// List what a selection matches without changing anything
+func (n *RaindropIOClient) previewSelection(ctx context.Context, collectionId int, sel SelectionType) (*BulkResultType, error) {
+	var affected []RaindropType
+
+	if sel.Search != "" {
+		all, err := n.GetAllRaindropsContext(ctx, collectionId, FilterType{Search: sel.Search})
+		if err != nil {
+			return nil, err
+		}
+		affected = all
+	}
+
+	for _, id := range sel.Ids {
+		res, err := n.GetRaindropContext(ctx, id)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// ids outside the collection are left alone by the API
+		if collectionId != COLLECTION_ALL && res.Item.CollectionId() != int64(collectionId) {
+			continue
+		}
+		affected = append(affected, res.Item)
+	}
+
+	result := &BulkResultType{DryRun: true, Affected: affected}
+	result.Result = true
+	result.Modified = int64(len(affected))
+	return result, nil
+}
@@ -0,0 +1,225 @@
+package raindropio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------------------
+// Client construction
+// ------------------------------------------------------------------------
+
+const DEFAULT_BASE_URL string = "https://api.raindrop.io/rest/v1/"
+const DEFAULT_TIMEOUT = 30 * time.Second
+const DEFAULT_USER_AGENT string = "nimbus-raindropio"
+
+type ClientOption func(*clientSettings) error
+
+// Everything the options can set, applied to the client in one go
+type clientSettings struct {
+	baseurl   string
+	tokens    TokenSource
+	timeout   time.Duration
+	userAgent string
+	transport http.RoundTripper
+	logger    *slog.Logger
+	retry     RetryPolicy
+	limiter   *RateLimiter
+}
+
+// Build a client. Without options it talks to DEFAULT_BASE_URL, waits
+// DEFAULT_TIMEOUT for each response, retries with DefaultRetryPolicy and
+// throttles on the rate limit headers; it still needs a token.
+/*
+	client, err := raindropio.NewClient(
+		raindropio.WithTokenSource(tokens),
+		raindropio.WithTimeout(10*time.Second),
+	)
+*/
+func NewClient(opts ...ClientOption) (*RaindropIOClient, error) {
+	settings := &clientSettings{
+		baseurl:   DEFAULT_BASE_URL,
+		timeout:   DEFAULT_TIMEOUT,
+		userAgent: DEFAULT_USER_AGENT,
+		transport: http.DefaultTransport,
+		retry:     DefaultRetryPolicy(),
+		limiter:   &RateLimiter{},
+	}
+	for _, opt := range opts {
+		if err := opt(settings); err != nil {
+			return nil, err
+		}
+	}
+
+	baseurl, err := normalizeBaseURL(settings.baseurl)
+	if err != nil {
+		return nil, err
+	}
+	if settings.tokens == nil {
+		return nil, fmt.Errorf("raindropio: a token source is required")
+	}
+
+	return &RaindropIOClient{
+		Baseurl:   baseurl,
+		Tokens:    settings.tokens,
+		Handle:    &http.Client{Transport: withHeaderTimeout(settings.transport, settings.timeout)},
+		UserAgent: settings.userAgent,
+		Logger:    settings.logger,
+		Retry:     settings.retry,
+		Limiter:   settings.limiter,
+	}, nil
+}
+
+// Routes are appended to the base url, so it must end with a slash
+func normalizeBaseURL(raw string) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("raindropio: invalid base url %q: %w", raw, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("raindropio: base url %q must be an absolute http(s) url", raw)
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("raindropio: base url %q cannot have a query or fragment", raw)
+	}
+
+	if !strings.HasSuffix(parsed.Path, "/") {
+		parsed.Path += "/"
+	}
+	return parsed.String(), nil
+}
+
+func WithBaseURL(baseurl string) ClientOption {
+	return func(s *clientSettings) error {
+		s.baseurl = baseurl
+		return nil
+	}
+}
+
+func WithTokenSource(tokens TokenSource) ClientOption {
+	return func(s *clientSettings) error {
+		s.tokens = tokens
+		return nil
+	}
+}
+
+// Shorthand for WithTokenSource(StaticToken(accessToken))
+func WithBearerToken(accessToken string) ClientOption {
+	return WithTokenSource(StaticToken(accessToken))
+}
+
+// Limit on waiting for the response to a single attempt of a request;
+// 0 means no limit. Reading the body is only bounded by the context, so
+// exports and backups can take as long as they need to download.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(s *clientSettings) error {
+		if timeout < 0 {
+			return fmt.Errorf("raindropio: negative timeout %s", timeout)
+		}
+		s.timeout = timeout
+		return nil
+	}
+}
+
+func WithUserAgent(userAgent string) ClientOption {
+	return func(s *clientSettings) error {
+		s.userAgent = userAgent
+		return nil
+	}
+}
+
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(s *clientSettings) error {
+		if transport == nil {
+			return fmt.Errorf("raindropio: nil transport")
+		}
+		s.transport = transport
+		return nil
+	}
+}
+
+// Requests are logged at debug level, retries at warn level
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(s *clientSettings) error {
+		s.logger = logger
+		return nil
+	}
+}
+
+// nil disables retries
+func WithRetryPolicy(retry RetryPolicy) ClientOption {
+	return func(s *clientSettings) error {
+		s.retry = retry
+		return nil
+	}
+}
+
+// nil disables throttling
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(s *clientSettings) error {
+		s.limiter = limiter
+		return nil
+	}
+}
+
+// ------------------------------------------------------------------------
+// Response timeout
+// ------------------------------------------------------------------------
+
+var errResponseTimeout = errors.New("raindropio: timed out waiting for the response")
+
+// http.Client.Timeout would also cut off reading the body, and
+// http.Transport.ResponseHeaderTimeout only exists on *http.Transport,
+// so the limit is enforced around any transport
+type headerTimeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func withHeaderTimeout(next http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	if timeout == 0 {
+		return next
+	}
+	return &headerTimeoutTransport{next: next, timeout: timeout}
+}
+
+func (t *headerTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(t.timeout, func() { cancel(errResponseTimeout) })
+
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() {
+		// fired while the response was arriving
+		if err == nil {
+			res.Body.Close()
+		}
+		cancel(nil)
+		return nil, fmt.Errorf("%w after %s", errResponseTimeout, t.timeout)
+	}
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+
+	// the context has to outlive RoundTrip until the body is done with
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelCauseFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
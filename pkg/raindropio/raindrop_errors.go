@@ -0,0 +1,96 @@
+package raindropio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ------------------------------------------------------------------------
+// API errors
+// ------------------------------------------------------------------------
+
+// Error returned when Raindrop.io rejects a request, either with a
+// 4xx/5xx status or with a `result: false` body.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Method     string
+	// Without its query, which may carry search terms or urls
+	Route string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" && e.StatusCode >= http.StatusBadRequest {
+		msg = http.StatusText(e.StatusCode)
+	} else if msg == "" {
+		msg = "request failed"
+	}
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+	return fmt.Sprintf("raindropio: %s %s: %d %s", e.Method, e.Route, e.StatusCode, msg)
+}
+
+// Body shape of a failed request
+type errorBodyType struct {
+	Result       *bool  `json:"result"`
+	Error        any    `json:"error"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// Build an *APIError out of a finished operation, or nil if the
+// request succeeded.
+func newAPIError(opRes *OperationResponseType, body []byte) *APIError {
+	status := opRes.response.StatusCode
+
+	var parsed errorBodyType
+	isJSON := json.Unmarshal(body, &parsed) == nil
+
+	failed := status >= http.StatusBadRequest
+	if isJSON && parsed.Result != nil && !*parsed.Result {
+		failed = true
+	}
+	if !failed {
+		return nil
+	}
+
+	route, _, _ := strings.Cut(opRes.route, "?")
+	apiErr := &APIError{
+		StatusCode: status,
+		Method:     opRes.method,
+		Route:      route,
+	}
+	if isJSON {
+		apiErr.Message = parsed.ErrorMessage
+		if parsed.Error != nil {
+			apiErr.Code = fmt.Sprint(parsed.Error)
+		}
+	}
+	return apiErr
+}
+
+// Check the status code of an error returned by the client
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// Report whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// Report whether err is a 401 from the API
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// Report whether err is a 429 from the API
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
@@ -0,0 +1,99 @@
+package raindropio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ------------------------------------------------------------------------
+// Exports and backups
+// ------------------------------------------------------------------------
+
+// Export formats
+const EXPORT_CSV string = "csv"
+const EXPORT_HTML string = "html"
+const EXPORT_ZIP string = "zip"
+
+// Export raindrops of a collection, streaming the file into w. zip
+// exports bundle saved files alongside the bookmarks.
+// Returns the number of bytes written.
+func (n *RaindropIOClient) ExportRaindrops(collectionId int, format string, filter FilterType, w io.Writer) (int64, error) {
+	return n.ExportRaindropsContext(context.Background(), collectionId, format, filter, w)
+}
+
+// ExportRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) ExportRaindropsContext(ctx context.Context, collectionId int, format string, filter FilterType, w io.Writer) (int64, error) {
+	if format != EXPORT_CSV && format != EXPORT_HTML && format != EXPORT_ZIP {
+		return 0, fmt.Errorf("raindropio: unsupported export format %q", format)
+	}
+
+	route := ROUTE_RAINDROPS + strconv.Itoa(collectionId) + "/" + ROUTE_EXPORT + "." + format + CreateFilterQuery(&filter)
+	opRes := n.ExecuteContext(ctx, "GET", route, nil)
+	return opRes.copyBody(w)
+}
+
+// List the backups of the account
+/*
+	[OUT] form:
+		result bool
+		items []{_id string, created string}
+*/
+func (n *RaindropIOClient) ListBackups() (*ItemsResponseType[BackupType], error) {
+	return n.ListBackupsContext(context.Background())
+}
+
+// ListBackups honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) ListBackupsContext(ctx context.Context) (*ItemsResponseType[BackupType], error) {
+	route := ROUTE_BACKUPS
+	return decodeResponse[ItemsResponseType[BackupType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Ask for a new backup. It is generated in the background and mailed to
+// the account owner, then shows up in ListBackups.
+func (n *RaindropIOClient) GenerateBackup() error {
+	return n.GenerateBackupContext(context.Background())
+}
+
+// GenerateBackup honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GenerateBackupContext(ctx context.Context) error {
+	route := ROUTE_BACKUP
+	opRes := n.ExecuteContext(ctx, "GET", route, nil)
+	// the body is a human readable notice, not JSON
+	_, err := opRes.readBody()
+	return err
+}
+
+// Download a backup as html or csv, streaming it into w.
+// Returns the number of bytes written.
+func (n *RaindropIOClient) DownloadBackup(id string, format string, w io.Writer) (int64, error) {
+	return n.DownloadBackupContext(context.Background(), id, format, w)
+}
+
+// DownloadBackup honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) DownloadBackupContext(ctx context.Context, id string, format string, w io.Writer) (int64, error) {
+	if format != EXPORT_CSV && format != EXPORT_HTML {
+		return 0, fmt.Errorf("raindropio: unsupported backup format %q", format)
+	}
+
+	route := ROUTE_BACKUP + id + "." + format
+	opRes := n.ExecuteContext(ctx, "GET", route, nil)
+	return opRes.copyBody(w)
+}
+
+// Stream a successful body into w without holding it in memory.
+// Error bodies are small, so those are still read whole.
+func (opRes *OperationResponseType) copyBody(w io.Writer) (int64, error) {
+	if opRes.err != nil {
+		return 0, opRes.err
+	}
+	if opRes.response.StatusCode >= http.StatusBadRequest {
+		_, err := opRes.readBody()
+		return 0, err
+	}
+
+	defer opRes.response.Body.Close()
+	return io.Copy(w, opRes.response.Body)
+}
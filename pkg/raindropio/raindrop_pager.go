@@ -0,0 +1,177 @@
+package raindropio
+
+import "context"
+
+// The API refuses pages larger than this
+const MAX_PER_PAGE int = 50
+
+// ------------------------------------------------------------------------
+// Raindrop pager
+// ------------------------------------------------------------------------
+
+// Walks every page of GetRaindrops for a collection and filter.
+/*
+	pager := client.NewRaindropPager(collectionId, filter)
+	for pager.Next(ctx) {
+		r := pager.Raindrop()
+	}
+	if err := pager.Err(); err != nil {
+		...
+	}
+*/
+type RaindropPager struct {
+	// Fetch the next page in the background while the current one is
+	// being consumed
+	Prefetch bool
+
+	client       *RaindropIOClient
+	collectionId int
+	filter       FilterType
+
+	items   []RaindropType
+	index   int
+	current RaindropType
+	pending <-chan pageResultType
+
+	page  int
+	seen  int64
+	count int64
+	done  bool
+	err   error
+}
+
+type pageResultType struct {
+	res *ItemsResponseType[RaindropType]
+	err error
+}
+
+// Create a pager starting at filter.Page. PerPage defaults to the
+// largest page the API allows.
+func (n *RaindropIOClient) NewRaindropPager(collectionId int, filter FilterType) *RaindropPager {
+	if filter.PerPage <= 0 || filter.PerPage > MAX_PER_PAGE {
+		filter.PerPage = MAX_PER_PAGE
+	}
+	if filter.Page < 0 {
+		filter.Page = 0
+	}
+
+	return &RaindropPager{
+		client:       n,
+		collectionId: collectionId,
+		filter:       filter,
+		page:         filter.Page,
+		// the skipped pages count towards the total
+		seen: int64(filter.Page * filter.PerPage),
+	}
+}
+
+// Advance to the next raindrop, fetching pages as needed. Returns false
+// once every page has been read or a request failed; check Err.
+func (p *RaindropPager) Next(ctx context.Context) bool {
+	for p.index >= len(p.items) {
+		if p.done || p.err != nil {
+			return false
+		}
+
+		var result pageResultType
+		if p.pending != nil {
+			result = <-p.pending
+			p.pending = nil
+		} else {
+			result = <-p.fetch(ctx, p.page)
+		}
+		if result.err != nil {
+			p.err = result.err
+			return false
+		}
+
+		p.items = result.res.Items
+		p.index = 0
+		p.count = result.res.Count
+		p.seen += int64(len(p.items))
+		p.page++
+
+		if len(p.items) < p.filter.PerPage || p.seen >= p.count {
+			p.done = true
+		} else if p.Prefetch {
+			p.pending = p.fetch(ctx, p.page)
+		}
+	}
+
+	p.current = p.items[p.index]
+	p.index++
+	return true
+}
+
+// The raindrop Next advanced to
+func (p *RaindropPager) Raindrop() RaindropType {
+	return p.current
+}
+
+// The first error hit while fetching pages
+func (p *RaindropPager) Err() error {
+	return p.err
+}
+
+// Total number of matching raindrops reported by the API
+func (p *RaindropPager) Count() int64 {
+	return p.count
+}
+
+func (p *RaindropPager) fetch(ctx context.Context, page int) <-chan pageResultType {
+	filter := p.filter
+	filter.Page = page
+
+	out := make(chan pageResultType, 1)
+	run := func() {
+		res, err := p.client.GetRaindropsContext(ctx, p.collectionId, filter)
+		out <- pageResultType{res: res, err: err}
+	}
+
+	if p.Prefetch {
+		go run()
+	} else {
+		run()
+	}
+	return out
+}
+
+// Read every page of a collection into memory
+func (n *RaindropIOClient) GetAllRaindrops(collectionId int, filter FilterType) ([]RaindropType, error) {
+	return n.GetAllRaindropsContext(context.Background(), collectionId, filter)
+}
+
+// GetAllRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetAllRaindropsContext(ctx context.Context, collectionId int, filter FilterType) ([]RaindropType, error) {
+	var all []RaindropType
+
+	pager := n.NewRaindropPager(collectionId, filter)
+	for pager.Next(ctx) {
+		all = append(all, pager.Raindrop())
+	}
+	return all, pager.Err()
+}
+
+// Read every raindrop of filter.CollectionId matching filter.Search,
+// narrowed down to filter.Ids when those are set.
+func (n *RaindropIOClient) FindRaindrops(filter FilterType) ([]RaindropType, error) {
+	return n.FindRaindropsContext(context.Background(), filter)
+}
+
+// FindRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) FindRaindropsContext(ctx context.Context, filter FilterType) ([]RaindropType, error) {
+	wanted := map[int64]bool{}
+	for _, id := range filter.Ids {
+		wanted[int64(id)] = true
+	}
+
+	var found []RaindropType
+	pager := n.NewRaindropPager(filter.CollectionId, filter)
+	for pager.Next(ctx) {
+		r := pager.Raindrop()
+		if len(wanted) == 0 || wanted[r.Id] {
+			found = append(found, r)
+		}
+	}
+	return found, pager.Err()
+}
@@ -0,0 +1,256 @@
+package raindropio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------------------------
+// Retry policies
+// ------------------------------------------------------------------------
+
+// Decides whether a finished attempt should be retried, and how long to
+// wait first. attempt starts at 0 for the first request; exactly one of
+// res and err is set.
+type RetryPolicy interface {
+	Backoff(attempt int, res *http.Response, err error) (time.Duration, bool)
+}
+
+// Retries transport errors, 429s and 5xxs with jittered exponential
+// backoff, honoring Retry-After when the API sends one.
+type BackoffPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+func DefaultRetryPolicy() *BackoffPolicy {
+	return &BackoffPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+func (p *BackoffPolicy) Backoff(attempt int, res *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries {
+		return 0, false
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+		return p.jittered(attempt), true
+	}
+
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < http.StatusInternalServerError {
+		return 0, false
+	}
+	if delay, ok := retryAfter(res, time.Now()); ok {
+		return min(delay, p.MaxDelay), true
+	}
+	return p.jittered(attempt), true
+}
+
+// Full jitter: a random delay between 0 and BaseDelay * 2^attempt
+func (p *BackoffPolicy) jittered(attempt int) time.Duration {
+	ceiling := p.BaseDelay << attempt
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+// Read how long the API asked us to wait, from Retry-After (seconds or
+// an HTTP date) or, failing that, the rate limit reset timestamp.
+func retryAfter(res *http.Response, now time.Time) (time.Duration, bool) {
+	if value := res.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+	if reset, ok := rateLimitReset(res); ok {
+		return max(reset.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// ------------------------------------------------------------------------
+// Rate limiting
+// ------------------------------------------------------------------------
+
+// Tracks the X-RateLimit-* headers of responses and holds requests back
+// once the current window is used up, instead of waiting for a 429.
+// Safe for concurrent use; share one per bearer token.
+type RateLimiter struct {
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+// Block until a request may be sent under the current window
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	delay := time.Duration(0)
+	if l.known {
+		now := time.Now()
+		if now.After(l.reset) {
+			l.known = false
+		} else if l.remaining <= 0 {
+			delay = l.reset.Sub(now)
+		} else {
+			l.remaining--
+		}
+	}
+	l.mu.Unlock()
+
+	return sleepContext(ctx, delay)
+}
+
+// Record the rate limit state reported by a response
+func (l *RateLimiter) Observe(res *http.Response) {
+	remaining, ok := rateLimitRemaining(res)
+	if !ok {
+		return
+	}
+	reset, ok := rateLimitReset(res)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	l.known = true
+	l.remaining = remaining
+	l.reset = reset
+	l.mu.Unlock()
+}
+
+func rateLimitRemaining(res *http.Response) (int, bool) {
+	value := res.Header.Get("X-RateLimit-Remaining")
+	if value == "" {
+		value = res.Header.Get("RateLimit-Remaining")
+	}
+	remaining, err := strconv.Atoi(value)
+	return remaining, err == nil
+}
+
+// X-RateLimit-Reset is a unix timestamp in seconds
+func rateLimitReset(res *http.Response) (time.Time, bool) {
+	value := res.Header.Get("X-RateLimit-Reset")
+	if value == "" {
+		value = res.Header.Get("RateLimit-Reset")
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// ------------------------------------------------------------------------
+// Sending
+// ------------------------------------------------------------------------
+
+// Only these are safe to send twice
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// Send a request through the client's rate limiter and retry policy
+func (n *RaindropIOClient) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if n.Limiter != nil {
+			if err := n.Limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		res, err := n.Handle.Do(req)
+		if n.Limiter != nil && res != nil {
+			n.Limiter.Observe(res)
+		}
+		if n.Logger != nil {
+			n.logAttempt(req, attempt, res, err)
+		}
+
+		if n.Retry == nil || !isIdempotent(req.Method) {
+			return res, err
+		}
+		delay, retry := n.Retry.Backoff(attempt, res, err)
+		if !retry {
+			return res, err
+		}
+
+		// the body has been consumed, rewind it for the next attempt
+		next := req.Clone(ctx)
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return res, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return res, err
+			}
+			next.Body = body
+		}
+
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		if n.Logger != nil {
+			n.Logger.WarnContext(ctx, "raindropio: retrying request",
+				"method", req.Method, "path", req.URL.Path, "attempt", attempt+1, "delay", delay)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		req = next
+	}
+}
+
+// The url is logged without its query, which may carry search terms
+func (n *RaindropIOClient) logAttempt(req *http.Request, attempt int, res *http.Response, err error) {
+	attrs := []any{"method", req.Method, "path", req.URL.Path, "attempt", attempt}
+	if err != nil {
+		n.Logger.DebugContext(req.Context(), "raindropio: request failed", append(attrs, "error", err)...)
+		return
+	}
+	n.Logger.DebugContext(req.Context(), "raindropio: request", append(attrs, "status", res.StatusCode)...)
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
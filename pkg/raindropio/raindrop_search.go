@@ -0,0 +1,127 @@
+package raindropio
+
+import (
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------------------------
+// Search queries
+// ------------------------------------------------------------------------
+
+// Raindrop types
+const TYPE_LINK string = "link"
+const TYPE_ARTICLE string = "article"
+const TYPE_IMAGE string = "image"
+const TYPE_VIDEO string = "video"
+const TYPE_DOCUMENT string = "document"
+const TYPE_AUDIO string = "audio"
+
+// Dates in search operators
+const SEARCH_DATE_LAYOUT string = "2006-01-02"
+
+// Builds the search string of a FilterType out of Raindrop's search
+// operators, quoting values so user input cannot inject operators.
+/*
+	filter.Search = NewSearch().
+		Tag("nmbs_inbox").
+		Type(TYPE_ARTICLE).
+		CreatedBefore(cutoff).
+		String()
+*/
+type SearchQuery struct {
+	terms []string
+}
+
+func NewSearch() *SearchQuery {
+	return &SearchQuery{}
+}
+
+// Free text; several words must all match
+func (q *SearchQuery) Text(text string) *SearchQuery {
+	for _, word := range strings.Fields(text) {
+		q.terms = append(q.terms, quote(strings.TrimLeft(word, "-#")))
+	}
+	return q
+}
+
+// An exact phrase
+func (q *SearchQuery) Phrase(phrase string) *SearchQuery {
+	return q.add(`"` + clean(phrase) + `"`)
+}
+
+func (q *SearchQuery) Tag(tag string) *SearchQuery {
+	return q.add("#" + quote(tag))
+}
+
+func (q *SearchQuery) WithoutTag(tag string) *SearchQuery {
+	return q.add("-#" + quote(tag))
+}
+
+// Raindrops with no tags at all
+func (q *SearchQuery) NoTag() *SearchQuery {
+	return q.add("notag:true")
+}
+
+// One of the TYPE_ constants
+func (q *SearchQuery) Type(kind string) *SearchQuery {
+	return q.add("type:" + quote(kind))
+}
+
+func (q *SearchQuery) Important(important bool) *SearchQuery {
+	if important {
+		return q.add("important:true")
+	}
+	return q.add("important:false")
+}
+
+func (q *SearchQuery) Domain(domain string) *SearchQuery {
+	return q.add("domain:" + quote(domain))
+}
+
+func (q *SearchQuery) WithoutDomain(domain string) *SearchQuery {
+	return q.add("-domain:" + quote(domain))
+}
+
+// Created on the given day
+func (q *SearchQuery) CreatedOn(day time.Time) *SearchQuery {
+	return q.add("created:" + day.Format(SEARCH_DATE_LAYOUT))
+}
+
+// Created after the given day
+func (q *SearchQuery) CreatedAfter(day time.Time) *SearchQuery {
+	return q.add("created:>" + day.Format(SEARCH_DATE_LAYOUT))
+}
+
+// Created before the given day
+func (q *SearchQuery) CreatedBefore(day time.Time) *SearchQuery {
+	return q.add("created:<" + day.Format(SEARCH_DATE_LAYOUT))
+}
+
+// An operator the builder has no method for, passed through as is
+func (q *SearchQuery) Raw(term string) *SearchQuery {
+	return q.add(term)
+}
+
+func (q *SearchQuery) String() string {
+	return strings.Join(q.terms, " ")
+}
+
+func (q *SearchQuery) add(term string) *SearchQuery {
+	q.terms = append(q.terms, term)
+	return q
+}
+
+// Quote values with spaces or colons; drop quotes that would end the
+// value early
+func quote(value string) string {
+	value = clean(value)
+	if strings.ContainsAny(value, " \t:") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+func clean(value string) string {
+	return strings.TrimSpace(strings.ReplaceAll(value, `"`, ""))
+}
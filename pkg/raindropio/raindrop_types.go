@@ -1,13 +1,25 @@
 package raindropio
 
-import "net/http"
+import (
+	"log/slog"
+	"net/http"
+)
 
 // ------------------------------------------------------------------------
 // Client type
+// Prefer NewClient over filling this in by hand.
 type RaindropIOClient struct {
 	Baseurl string
-	Bearer  string
-	Handle  *http.Client
+	// Full Authorization header, used when Tokens is nil
+	Bearer    string
+	Tokens    TokenSource
+	Handle    *http.Client
+	UserAgent string
+	Logger    *slog.Logger
+
+	// Optional; requests are sent once, unthrottled, when these are nil
+	Retry   RetryPolicy
+	Limiter *RateLimiter
 }
 
 // ------------------------------------------------------------------------
@@ -15,40 +27,101 @@ type RaindropIOClient struct {
 // ------------------------------------------------------------------------
 
 type CollectionType struct {
-	Id            string               `json:"_id,omitempty"`
-	Access        CollectionAccessType `json:"access,omitempty"`
-	Collaborators CollaboratorsType    `json:"collaborators,omitempty"`
-	Color         string               `json:"color,omitempty"`
-	Count         int64                `json:"count,omitempty"`
-	Cover         []string             `json:"cover,omitempty"`
-	Created       string               `json:"created,omitempty"`
-	Expanded      bool                 `json:"expanded,omitempty"`
-	LastUpdate    string               `json:"lastUpdate,omitempty"`
-	Parent        CollectionParentType `json:"parent,omitempty"`
-	Public        bool                 `json:"public,omitempty"`
-	Sort          int64                `json:"sort,omitempty"`
-	Title         string               `json:"title,omitempty"`
-	User          UserType             `json:"user,omitempty"`
-	View          string               `json:"view,omitempty"`
+	Id            int64                 `json:"_id,omitempty"`
+	Access        *CollectionAccessType `json:"access,omitempty"`
+	Author        bool                  `json:"author,omitempty"`
+	Collaborators *CollaboratorsRefType `json:"collaborators,omitempty"`
+	Color         string                `json:"color,omitempty"`
+	Count         int64                 `json:"count,omitempty"`
+	Cover         []string              `json:"cover,omitempty"`
+	Created       string                `json:"created,omitempty"`
+	CreatorRef    *CreatorRefType       `json:"creatorRef,omitempty"`
+	Expanded      bool                  `json:"expanded,omitempty"`
+	LastAction    string                `json:"lastAction,omitempty"`
+	LastUpdate    string                `json:"lastUpdate,omitempty"`
+	Parent        *CollectionParentType `json:"parent,omitempty"`
+	Public        bool                  `json:"public,omitempty"`
+	Slug          string                `json:"slug,omitempty"`
+	Sort          int64                 `json:"sort,omitempty"`
+	Title         string                `json:"title,omitempty"`
+	User          *UserType             `json:"user,omitempty"`
+	View          string                `json:"view,omitempty"`
+}
+
+type MergeCollectionsBody struct {
+	To  int   `json:"to"`
+	Ids []int `json:"ids"`
+}
+
+type SortBody struct {
+	Sort string `json:"sort"`
+}
+
+type SharingBody struct {
+	Role   string   `json:"role,omitempty"`
+	Emails []string `json:"emails,omitempty"`
+}
+
+type SharingResponseType struct {
+	ResultResponseType
+	Emails []string `json:"emails,omitempty"`
+}
+
+type JoinBody struct {
+	Token string `json:"token"`
+}
+
+type JoinResponseType struct {
+	ResultResponseType
+	RoleJoined string `json:"roleJoined,omitempty"`
+}
+
+type CoverGroupType struct {
+	Title string `json:"title"`
+	Icons []struct {
+		Png string `json:"png"`
+	} `json:"icons"`
 }
 
 type CollectionAccessType struct {
+	For       int64 `json:"for,omitempty"`
 	Level     int64 `json:"level,omitempty"`
+	Root      bool  `json:"root,omitempty"`
 	Draggable bool  `json:"draggable,omitempty"`
 }
 
 type CollectionParentType struct {
 	Id int64 `json:"$id,omitempty"`
 }
+
+type CollaboratorsRefType struct {
+	Id string `json:"$id,omitempty"`
+}
+
 type HighlightType struct {
-	Id      string   `json:"_id,omitempty"`
-	Text    string   `json:"text,omitempty"`
-	Title   string   `json:"title,omitempty"`
-	Color   string   `json:"color,omitempty"`
-	Note    string   `json:"note,omitempty"`
-	Created string   `json:"created,omitempty"`
-	Tags    []string `json:"tags,omitempty"`
-	Link    string   `json:"link,omitempty"`
+	Id          string   `json:"_id,omitempty"`
+	Text        string   `json:"text,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Color       string   `json:"color,omitempty"`
+	Note        string   `json:"note,omitempty"`
+	Created     string   `json:"created,omitempty"`
+	LastUpdate  string   `json:"lastUpdate,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Link        string   `json:"link,omitempty"`
+	RaindropRef int64    `json:"raindropRef,omitempty"`
+}
+
+// Highlight as sent when editing a raindrop; an empty Text removes it
+type HighlightEditType struct {
+	Id    string   `json:"_id,omitempty"`
+	Text  *string  `json:"text,omitempty"`
+	Color string   `json:"color,omitempty"`
+	Note  string   `json:"note,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+type HighlightsBody struct {
+	Highlights []HighlightEditType `json:"highlights"`
 }
 
 type ReminderType struct {
@@ -59,29 +132,79 @@ type ReminderType struct {
 // Raindrop types
 // ------------------------------------------------------------------------
 type RaindropType struct {
-	Created    string               `json:"created,omitempty"`
-	LastUpdate string               `json:"lastUpdate,omitempty"`
-	Order      int64                `json:"order,omitempty,string"`
-	Important  bool                 `json:"important,omitempty,string"`
-	Tags       []string             `json:"tags,omitempty"`
-	Media      []string             `json:"media,omitempty"`
-	Cover      string               `json:"cover,omitempty"`
-	Collection CollectionParentType `json:"collection,omitempty"`
-	Type       string               `json:"type,omitempty"`
-	Excerpt    string               `json:"excerpt,omitempty"`
-	Title      string               `json:"title,omitempty"`
-	Link       string               `json:"link,omitempty"`
-	Highlights []string             `json:"highlights,omitempty"`
-	Reminder   ReminderType         `json:"reminder,omitempty"`
+	Id         int64                 `json:"_id,omitempty"`
+	Created    string                `json:"created,omitempty"`
+	LastUpdate string                `json:"lastUpdate,omitempty"`
+	Order      int64                 `json:"order,omitempty"`
+	Sort       int64                 `json:"sort,omitempty"`
+	Important  bool                  `json:"important,omitempty"`
+	Broken     bool                  `json:"broken,omitempty"`
+	Removed    bool                  `json:"removed,omitempty"`
+	Tags       []string              `json:"tags,omitempty"`
+	Media      []MediaType           `json:"media,omitempty"`
+	Cover      string                `json:"cover,omitempty"`
+	Collection *CollectionParentType `json:"collection,omitempty"`
+	User       *UserType             `json:"user,omitempty"`
+	CreatorRef *CreatorRefType       `json:"creatorRef,omitempty"`
+	Type       string                `json:"type,omitempty"`
+	Domain     string                `json:"domain,omitempty"`
+	Excerpt    string                `json:"excerpt,omitempty"`
+	Note       string                `json:"note,omitempty"`
+	Title      string                `json:"title,omitempty"`
+	Link       string                `json:"link,omitempty"`
+	File       *FileType             `json:"file,omitempty"`
+	Cache      *CacheType            `json:"cache,omitempty"`
+	Highlights []HighlightType       `json:"highlights,omitempty"`
+	Reminder   *ReminderType         `json:"reminder,omitempty"`
+	// Only sent on create; asks the API to fill in title, cover, ...
+	PleaseParse *struct{} `json:"pleaseParse,omitempty"`
+}
+
+// Id of the collection holding the raindrop, 0 when unknown
+func (r *RaindropType) CollectionId() int64 {
+	if r.Collection == nil {
+		return 0
+	}
+	return r.Collection.Id
+}
+
+type MediaType struct {
+	Link string `json:"link"`
+	Type string `json:"type,omitempty"`
+}
+
+type FileType struct {
+	Name string `json:"name,omitempty"`
+	Size int64  `json:"size,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+// Permanent copy of the page
+type CacheType struct {
+	Status  string `json:"status,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Created string `json:"created,omitempty"`
+}
+
+// Changes to a single raindrop, for PatchRaindrop. Only fields that are
+// set are sent, so Tags can be sent empty to clear every tag, which
+// RaindropType's omitempty cannot express.
+type RaindropPatchType struct {
+	Title      *string               `json:"title,omitempty"`
+	Note       *string               `json:"note,omitempty"`
+	Tags       *[]string             `json:"tags,omitempty"`
+	Important  *bool                 `json:"important,omitempty"`
+	Collection *CollectionParentType `json:"collection,omitempty"`
+	Reminder   *ReminderType         `json:"reminder,omitempty"`
 }
 
 type RaindropUpdateType struct {
-	Ids        []int                `json:"ids,omitempty"`
-	Important  bool                 `json:"important,omitempty"`
-	Tags       []string             `json:"tags,omitempty"`
-	Media      []any                `json:"media,omitempty"`
-	Cover      string               `json:"cover,omitempty"`
-	Collection CollectionParentType `json:"collection,omitempty"`
+	Ids        []int                 `json:"ids,omitempty"`
+	Important  bool                  `json:"important,omitempty"`
+	Tags       []string              `json:"tags,omitempty"`
+	Media      []any                 `json:"media,omitempty"`
+	Cover      string                `json:"cover,omitempty"`
+	Collection *CollectionParentType `json:"collection,omitempty"`
 }
 
 type FilterType struct {
@@ -91,6 +214,145 @@ type FilterType struct {
 	Page         int    `json:"page,omitempty"`
 	PerPage      int    `json:"perpage,omitempty"`
 	Ids          []int  `json:"ids,omitempty"`
+	// Include raindrops of child collections
+	Nested bool `json:"nested,omitempty"`
+}
+
+// ------------------------------------------------------------------------
+// Tag types
+// ------------------------------------------------------------------------
+
+type TagType struct {
+	Id    string `json:"_id"`
+	Count int64  `json:"count,omitempty"`
+}
+
+type TagsBody struct {
+	Replace string   `json:"replace,omitempty"`
+	Tags    []string `json:"tags"`
+}
+
+// ------------------------------------------------------------------------
+// User types
+// ------------------------------------------------------------------------
+
+type UserProfileType struct {
+	Id         int64           `json:"_id,omitempty"`
+	Email      string          `json:"email,omitempty"`
+	EmailMD5   string          `json:"email_MD5,omitempty"`
+	FullName   string          `json:"fullName,omitempty"`
+	Password   bool            `json:"password,omitempty"`
+	Pro        bool            `json:"pro,omitempty"`
+	ProExpire  string          `json:"proExpire,omitempty"`
+	Registered string          `json:"registered,omitempty"`
+	Config     *UserConfigType `json:"config,omitempty"`
+	Files      *UserFilesType  `json:"files,omitempty"`
+	Groups     []UserGroupType `json:"groups,omitempty"`
+}
+
+type UserConfigType struct {
+	BrokenLevel    string `json:"broken_level,omitempty"`
+	FontColor      string `json:"font_color,omitempty"`
+	FontSize       int64  `json:"font_size,omitempty"`
+	Lang           string `json:"lang,omitempty"`
+	LastCollection int64  `json:"last_collection,omitempty"`
+	RaindropsSort  string `json:"raindrops_sort,omitempty"`
+	RaindropsView  string `json:"raindrops_view,omitempty"`
+}
+
+type UserFilesType struct {
+	Used           int64  `json:"used"`
+	Size           int64  `json:"size"`
+	LastCheckPoint string `json:"lastCheckPoint,omitempty"`
+}
+
+type UserGroupType struct {
+	Title       string  `json:"title"`
+	Hidden      bool    `json:"hidden,omitempty"`
+	Sort        int64   `json:"sort,omitempty"`
+	Collections []int64 `json:"collections,omitempty"`
+}
+
+type UserResponseType struct {
+	ResultResponseType
+	User UserProfileType `json:"user"`
+}
+
+type CountType struct {
+	Id    int64 `json:"_id,omitempty"`
+	Count int64 `json:"count"`
+}
+
+type UserStatsResponseType struct {
+	ResultResponseType
+	Items []CountType `json:"items"`
+	Meta  struct {
+		Id                   int64     `json:"_id"`
+		Pro                  bool      `json:"pro"`
+		ChangedBookmarksDate string    `json:"changedBookmarksDate,omitempty"`
+		Duplicates           CountType `json:"duplicates"`
+		Broken               CountType `json:"broken"`
+	} `json:"meta"`
+}
+
+// Returned by the filters route of a collection
+type CollectionStatsType struct {
+	ResultResponseType
+	Broken     CountType `json:"broken"`
+	Duplicates CountType `json:"duplicates"`
+	Important  CountType `json:"important"`
+	NoTag      CountType `json:"notag"`
+	Tags       []TagType `json:"tags,omitempty"`
+	Types      []TagType `json:"types,omitempty"`
+}
+
+// ------------------------------------------------------------------------
+// Import types
+// ------------------------------------------------------------------------
+
+type ParsedURLType struct {
+	Title   string `json:"title,omitempty"`
+	Excerpt string `json:"excerpt,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Meta    struct {
+		Canonical            string   `json:"canonical,omitempty"`
+		Site                 string   `json:"site,omitempty"`
+		Tags                 []string `json:"tags,omitempty"`
+		PossibleTitles       []string `json:"possibleTitles,omitempty"`
+		PossibleDescriptions []string `json:"possibleDescriptions,omitempty"`
+	} `json:"meta"`
+	Media []struct {
+		Link string `json:"link"`
+		Type string `json:"type,omitempty"`
+	} `json:"media,omitempty"`
+}
+
+type URLsBody struct {
+	Urls []string `json:"urls"`
+}
+
+type URLsExistResponseType struct {
+	ResultResponseType
+	Ids        []int64 `json:"ids"`
+	Duplicates []struct {
+		Id   int64  `json:"_id"`
+		Link string `json:"link"`
+	} `json:"duplicates,omitempty"`
+}
+
+type ImportFolderType struct {
+	Title     string             `json:"title"`
+	Folders   []ImportFolderType `json:"folders,omitempty"`
+	Bookmarks []RaindropType     `json:"bookmarks,omitempty"`
+}
+
+// ------------------------------------------------------------------------
+// Backup types
+// ------------------------------------------------------------------------
+
+type BackupType struct {
+	Id      string `json:"_id"`
+	Created string `json:"created,omitempty"`
 }
 
 // ------------------------------------------------------------------------
@@ -101,6 +363,14 @@ type UserType struct {
 	Id int64 `json:"$id,omitempty"`
 }
 
+// Who created an item in a shared collection
+type CreatorRefType struct {
+	Id     int64  `json:"_id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+	Email  string `json:"email,omitempty"`
+}
+
 type CollaboratorsType struct {
 	Id       string `json:"_id,omitempty"`
 	Email    string `json:"email,omitempty"`
@@ -121,9 +391,47 @@ type ListBody struct {
 	Items []RaindropType `json:"items,omitempty"`
 }
 
+type SuggestionsType struct {
+	Collections []CollectionParentType `json:"collections,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+}
+
+// ------------------------------------------------------------------------
+// Response envelopes
+// ------------------------------------------------------------------------
+
+type ResultResponseType struct {
+	Result       bool   `json:"result"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+type CountResponseType struct {
+	ResultResponseType
+	Count int64 `json:"count"`
+}
+
+type ModifiedResponseType struct {
+	ResultResponseType
+	Modified int64 `json:"modified"`
+}
+
+type ItemResponseType[T any] struct {
+	ResultResponseType
+	Item T `json:"item"`
+}
+
+type ItemsResponseType[T any] struct {
+	ResultResponseType
+	Items        []T   `json:"items"`
+	Count        int64 `json:"count,omitempty"`
+	CollectionId int64 `json:"collectionId,omitempty"`
+}
+
 // ------------------------------------------------------------------------
 // http extention
 type OperationResponseType struct {
 	response *http.Response
 	err      error
+	method   string
+	route    string
 }
@@ -0,0 +1,148 @@
+package raindropio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ------------------------------------------------------------------------
+// Uploads
+// ------------------------------------------------------------------------
+
+// Create a raindrop from a file (PDF, image, ...). The file is streamed
+// from r, so the upload is never retried.
+/*
+	[IN] form (multipart):
+		file binary
+		collectionId int
+
+	[OUT] form:
+		result bool
+		item RaindropType
+*/
+func (n *RaindropIOClient) UploadFile(collectionId int, name string, r io.Reader) (*ItemResponseType[RaindropType], error) {
+	return n.UploadFileContext(context.Background(), collectionId, name, r)
+}
+
+// UploadFile honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UploadFileContext(ctx context.Context, collectionId int, name string, r io.Reader) (*ItemResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROP + ROUTE_FILE
+	fields := map[string]string{"collectionId": strconv.Itoa(collectionId)}
+	return decodeResponse[ItemResponseType[RaindropType]](n.executeMultipart(ctx, "PUT", route, "file", name, r, fields))
+}
+
+// Create a raindrop from a file on disk
+func (n *RaindropIOClient) UploadLocalFile(collectionId int, path string) (*ItemResponseType[RaindropType], error) {
+	return n.UploadLocalFileContext(context.Background(), collectionId, path)
+}
+
+// UploadLocalFile honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UploadLocalFileContext(ctx context.Context, collectionId int, path string) (*ItemResponseType[RaindropType], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return n.UploadFileContext(ctx, collectionId, filepath.Base(path), file)
+}
+
+// Upload a cover image for a raindrop
+/*
+	[IN] form (multipart):
+		cover binary
+
+	[OUT] form:
+		result bool
+		item RaindropType
+*/
+func (n *RaindropIOClient) UploadRaindropCover(id int, name string, r io.Reader) (*ItemResponseType[RaindropType], error) {
+	return n.UploadRaindropCoverContext(context.Background(), id, name, r)
+}
+
+// UploadRaindropCover honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UploadRaindropCoverContext(ctx context.Context, id int, name string, r io.Reader) (*ItemResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROP + strconv.Itoa(id) + "/" + ROUTE_COVER
+	return decodeResponse[ItemResponseType[RaindropType]](n.executeMultipart(ctx, "PUT", route, "cover", name, r, nil))
+}
+
+// Upload a cover image for a collection
+/*
+	[IN] form (multipart):
+		cover binary
+
+	[OUT] form:
+		result bool
+		item CollectionType
+*/
+func (n *RaindropIOClient) UploadCollectionCover(id int, name string, r io.Reader) (*ItemResponseType[CollectionType], error) {
+	return n.UploadCollectionCoverContext(context.Background(), id, name, r)
+}
+
+// UploadCollectionCover honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UploadCollectionCoverContext(ctx context.Context, id int, name string, r io.Reader) (*ItemResponseType[CollectionType], error) {
+	route := ROUTE_COLLECTION + strconv.Itoa(id) + "/" + ROUTE_COVER
+	return decodeResponse[ItemResponseType[CollectionType]](n.executeMultipart(ctx, "PUT", route, "cover", name, r, nil))
+}
+
+// Stream r as a single multipart file part, alongside plain fields
+func (n *RaindropIOClient) executeMultipart(ctx context.Context, method string, route string, field string, name string, r io.Reader, fields map[string]string) OperationResponseType {
+	// peeking does not consume what the part copies below
+	buffered := bufio.NewReaderSize(r, 512)
+	contentType := detectContentType(name, buffered)
+
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+
+	go func() {
+		pw.CloseWithError(writeMultipart(form, field, name, contentType, buffered, fields))
+	}()
+
+	opRes := n.executeBody(ctx, method, route, form.FormDataContentType(), pr)
+	// unblock the writer if the request never read the whole body
+	pr.Close()
+	return opRes
+}
+
+func writeMultipart(form *multipart.Writer, field string, name string, contentType string, r io.Reader, fields map[string]string) error {
+	for key, value := range fields {
+		if err := form.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": field, "filename": name}))
+	header.Set("Content-Type", contentType)
+
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// Sniff the first bytes of the upload, falling back to the file
+// extension when the content alone is not conclusive.
+func detectContentType(name string, r *bufio.Reader) string {
+	head, _ := r.Peek(512)
+	sniffed := http.DetectContentType(head)
+	if sniffed != "application/octet-stream" && sniffed != "text/plain; charset=utf-8" {
+		return sniffed
+	}
+	if byExt := mime.TypeByExtension(filepath.Ext(name)); byExt != "" {
+		return byExt
+	}
+	return sniffed
+}
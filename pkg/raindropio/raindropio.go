@@ -2,11 +2,13 @@
 package raindropio
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
-	"strings"
 )
 
 // ------------------------------------------------------------------------
@@ -19,14 +21,37 @@ const ROUTE_MERGE string = "merge/"
 const ROUTE_RAINDROP string = "raindrop/"
 const ROUTE_RAINDROPS string = "raindrops/"
 const ROUTE_SUGGEST string = "suggest/"
+const ROUTE_TAGS string = "tags/"
+const ROUTE_HIGHLIGHTS string = "highlights/"
+const ROUTE_USER string = "user/"
+const ROUTE_STATS string = "stats/"
+const ROUTE_FILTERS string = "filters/"
+const ROUTE_CLEAN string = "clean/"
+const ROUTE_SHARING string = "sharing/"
+const ROUTE_JOIN string = "join/"
+const ROUTE_COVERS string = "covers/"
+const ROUTE_FILE string = "file/"
+const ROUTE_COVER string = "cover/"
+const ROUTE_IMPORT string = "import/"
+const ROUTE_URL string = "url/"
+const ROUTE_PARSE string = "parse/"
+const ROUTE_EXISTS string = "exists/"
+const ROUTE_EXPORT string = "export"
+const ROUTE_BACKUP string = "backup/"
+const ROUTE_BACKUPS string = "backups/"
+
+// System collections
+const COLLECTION_ALL int = 0
+const COLLECTION_UNSORTED int = -1
+const COLLECTION_TRASH int = -99
 
 // ------------------------------------------------------------------------
 // Operations
 type Operation interface {
-	GetChildCollections() OperationResponseType
-	GetCollectionById(id string) OperationResponseType
-	GetRaindropById(id string) OperationResponseType
-	CreateRaindrop(r RaindropType) OperationResponseType
+	GetChildCollections() (*ItemsResponseType[CollectionType], error)
+	GetCollection(id int) (*ItemResponseType[CollectionType], error)
+	GetRaindrop(id int) (*ItemResponseType[RaindropType], error)
+	CreateRaindrop(in RaindropType) (*ItemResponseType[RaindropType], error)
 }
 
 // -------------------------------------------------------------------------
@@ -54,20 +79,14 @@ type Operation interface {
 			view string
 
 */
-func (n *RaindropIOClient) GetChildCollections() OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_COLLECTIONS + ROUTE_CHILDRENS
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("GET", n.Baseurl+route, nil)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	req.Header.Add("Authorization", n.Bearer)
+func (n *RaindropIOClient) GetChildCollections() (*ItemsResponseType[CollectionType], error) {
+	return n.GetChildCollectionsContext(context.Background())
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// GetChildCollections honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetChildCollectionsContext(ctx context.Context) (*ItemsResponseType[CollectionType], error) {
+	route := ROUTE_COLLECTIONS + ROUTE_CHILDRENS
+	return decodeResponse[ItemsResponseType[CollectionType]](n.ExecuteContext(ctx, "GET", route, nil))
 }
 
 // Get collection
@@ -91,19 +110,14 @@ func (n *RaindropIOClient) GetChildCollections() OperationResponseType {
 			view string
 
 */
-func (n *RaindropIOClient) GetCollection(id int) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_COLLECTION
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("GET", n.Baseurl+route+strconv.Itoa(id), nil)
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Add("Authorization", n.Bearer)
+func (n *RaindropIOClient) GetCollection(id int) (*ItemResponseType[CollectionType], error) {
+	return n.GetCollectionContext(context.Background(), id)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// GetCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetCollectionContext(ctx context.Context, id int) (*ItemResponseType[CollectionType], error) {
+	route := ROUTE_COLLECTION + strconv.Itoa(id)
+	return decodeResponse[ItemResponseType[CollectionType]](n.ExecuteContext(ctx, "GET", route, nil))
 }
 
 // Get root collections
@@ -127,19 +141,14 @@ func (n *RaindropIOClient) GetCollection(id int) OperationResponseType {
 			view string
 
 */
-func (n *RaindropIOClient) GetRootCollections() OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_COLLECTIONS
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("GET", n.Baseurl+route, nil)
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Add("Authorization", n.Bearer)
+func (n *RaindropIOClient) GetRootCollections() (*ItemsResponseType[CollectionType], error) {
+	return n.GetRootCollectionsContext(context.Background())
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// GetRootCollections honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetRootCollectionsContext(ctx context.Context) (*ItemsResponseType[CollectionType], error) {
+	route := ROUTE_COLLECTIONS
+	return decodeResponse[ItemsResponseType[CollectionType]](n.ExecuteContext(ctx, "GET", route, nil))
 }
 
 // Create Collection
@@ -156,26 +165,14 @@ func (n *RaindropIOClient) GetRootCollections() OperationResponseType {
 		result bool
 		item JSON['CollectionType']
 */
-func (n *RaindropIOClient) CreateCollection(in CollectionType) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_COLLECTION
-
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(in)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("POST", n.Baseurl+route, strings.NewReader(string(parsed)))
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Add("Authorization", n.Bearer)
-	req.Header.Add("Content-Type", "application/json")
+func (n *RaindropIOClient) CreateCollection(in CollectionType) (*ItemResponseType[CollectionType], error) {
+	return n.CreateCollectionContext(context.Background(), in)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// CreateCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) CreateCollectionContext(ctx context.Context, in CollectionType) (*ItemResponseType[CollectionType], error) {
+	route := ROUTE_COLLECTION
+	return decodeResponse[ItemResponseType[CollectionType]](n.ExecuteContext(ctx, "POST", route, in))
 }
 
 // Update an existing collection
@@ -192,26 +189,14 @@ func (n *RaindropIOClient) CreateCollection(in CollectionType) OperationResponse
 		result bool
 		item JSON['CollectionType']
 */
-func (n *RaindropIOClient) UpdateCollection(id int, in CollectionType) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_COLLECTION
-
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(in)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("PUT", n.Baseurl+route+strconv.Itoa(id), strings.NewReader(string(parsed)))
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Add("Authorization", n.Bearer)
-	req.Header.Add("Content-Type", "application/json")
+func (n *RaindropIOClient) UpdateCollection(id int, in CollectionType) (*ItemResponseType[CollectionType], error) {
+	return n.UpdateCollectionContext(context.Background(), id, in)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// UpdateCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UpdateCollectionContext(ctx context.Context, id int, in CollectionType) (*ItemResponseType[CollectionType], error) {
+	route := ROUTE_COLLECTION + strconv.Itoa(id)
+	return decodeResponse[ItemResponseType[CollectionType]](n.ExecuteContext(ctx, "PUT", route, in))
 }
 
 // Remove collection
@@ -219,46 +204,230 @@ func (n *RaindropIOClient) UpdateCollection(id int, in CollectionType) Operation
 	[OUT] form:
 		result bool
 */
-func (n *RaindropIOClient) RemoveCollection(id int) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_COLLECTION
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("DELETE", n.Baseurl+route+strconv.Itoa(id), nil)
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Add("Authorization", n.Bearer)
+func (n *RaindropIOClient) RemoveCollection(id int) (*ResultResponseType, error) {
+	return n.RemoveCollectionContext(context.Background(), id)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// RemoveCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveCollectionContext(ctx context.Context, id int) (*ResultResponseType, error) {
+	route := ROUTE_COLLECTION + strconv.Itoa(id)
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "DELETE", route, nil))
 }
 
 // Remove multiple collections
 /*
 	[IN] form:
 		ids []string
+
+	[OUT] form:
+		result bool
+		modified int
+*/
+func (n *RaindropIOClient) RemoveMultipleCollections(in IDList) (*ModifiedResponseType, error) {
+	return n.RemoveMultipleCollectionsContext(context.Background(), in)
+}
+
+// RemoveMultipleCollections honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveMultipleCollectionsContext(ctx context.Context, in IDList) (*ModifiedResponseType, error) {
+	route := ROUTE_COLLECTIONS
+	return decodeResponse[ModifiedResponseType](n.ExecuteContext(ctx, "DELETE", route, in))
+}
+
+// Merge collections into one
+/*
+	[IN] form:
+		to int
+		ids []int
+
+	[OUT] form:
+		result bool
+		modified int
+*/
+func (n *RaindropIOClient) MergeCollections(to int, ids []int) (*ModifiedResponseType, error) {
+	return n.MergeCollectionsContext(context.Background(), to, ids)
+}
+
+// MergeCollections honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) MergeCollectionsContext(ctx context.Context, to int, ids []int) (*ModifiedResponseType, error) {
+	route := ROUTE_COLLECTIONS + ROUTE_MERGE
+	in := MergeCollectionsBody{To: to, Ids: ids}
+	return decodeResponse[ModifiedResponseType](n.ExecuteContext(ctx, "PUT", route, in))
+}
+
+// Reorder all root collections
+/*
+	[IN] form:
+		sort string (title, -title or -count)
+
+	[OUT] form:
+		result bool
 */
-func (n *RaindropIOClient) RemoveMultipleCollections(in IDList) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
+func (n *RaindropIOClient) SortCollections(sort string) (*ResultResponseType, error) {
+	return n.SortCollectionsContext(context.Background(), sort)
+}
+
+// SortCollections honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) SortCollectionsContext(ctx context.Context, sort string) (*ResultResponseType, error) {
 	route := ROUTE_COLLECTIONS
+	in := SortBody{Sort: sort}
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "PUT", route, in))
+}
 
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(in)
-	if opRes.err != nil {
-		return opRes
-	}
+// Remove all empty collections
+/*
+	[OUT] form:
+		result bool
+		count int
+*/
+func (n *RaindropIOClient) RemoveEmptyCollections() (*CountResponseType, error) {
+	return n.RemoveEmptyCollectionsContext(context.Background())
+}
 
-	var req *http.Request
-	req, opRes.err = http.NewRequest("DELETE", n.Baseurl+route, strings.NewReader(string(parsed)))
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Add("Authorization", n.Bearer)
-	req.Header.Add("Content-Type", "application/json")
+// RemoveEmptyCollections honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveEmptyCollectionsContext(ctx context.Context) (*CountResponseType, error) {
+	route := ROUTE_COLLECTIONS + ROUTE_CLEAN
+	return decodeResponse[CountResponseType](n.ExecuteContext(ctx, "PUT", route, nil))
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// Permanently remove everything in the trash
+/*
+	[OUT] form:
+		result bool
+*/
+func (n *RaindropIOClient) EmptyTrash() (*ResultResponseType, error) {
+	return n.EmptyTrashContext(context.Background())
+}
+
+// EmptyTrash honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) EmptyTrashContext(ctx context.Context) (*ResultResponseType, error) {
+	return n.RemoveCollectionContext(ctx, COLLECTION_TRASH)
+}
+
+// Share a collection by email
+/*
+	[IN] form:
+		role string (member or viewer)
+		emails []string
+
+	[OUT] form:
+		result bool
+		emails []string
+*/
+func (n *RaindropIOClient) ShareCollection(id int, in SharingBody) (*SharingResponseType, error) {
+	return n.ShareCollectionContext(context.Background(), id, in)
+}
+
+// ShareCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) ShareCollectionContext(ctx context.Context, id int, in SharingBody) (*SharingResponseType, error) {
+	route := sharingRoute(id)
+	return decodeResponse[SharingResponseType](n.ExecuteContext(ctx, "POST", route, in))
+}
+
+// Get collaborators of a collection
+/*
+	[OUT] form:
+		result bool
+		items []CollaboratorsType
+*/
+func (n *RaindropIOClient) GetCollaborators(id int) (*ItemsResponseType[CollaboratorsType], error) {
+	return n.GetCollaboratorsContext(context.Background(), id)
+}
+
+// GetCollaborators honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetCollaboratorsContext(ctx context.Context, id int) (*ItemsResponseType[CollaboratorsType], error) {
+	route := sharingRoute(id)
+	return decodeResponse[ItemsResponseType[CollaboratorsType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Unshare a collection, or leave it when the token belongs to a collaborator
+/*
+	[OUT] form:
+		result bool
+*/
+func (n *RaindropIOClient) UnshareCollection(id int) (*ResultResponseType, error) {
+	return n.UnshareCollectionContext(context.Background(), id)
+}
+
+// UnshareCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UnshareCollectionContext(ctx context.Context, id int) (*ResultResponseType, error) {
+	route := sharingRoute(id)
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "DELETE", route, nil))
+}
+
+// Change the role of a collaborator
+/*
+	[IN] form:
+		role string (member or viewer)
+
+	[OUT] form:
+		result bool
+*/
+func (n *RaindropIOClient) UpdateCollaborator(id int, userId int, role string) (*ResultResponseType, error) {
+	return n.UpdateCollaboratorContext(context.Background(), id, userId, role)
+}
+
+// UpdateCollaborator honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UpdateCollaboratorContext(ctx context.Context, id int, userId int, role string) (*ResultResponseType, error) {
+	route := sharingRoute(id) + strconv.Itoa(userId)
+	in := SharingBody{Role: role}
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "PUT", route, in))
+}
+
+// Remove a collaborator from a collection
+/*
+	[OUT] form:
+		result bool
+*/
+func (n *RaindropIOClient) RemoveCollaborator(id int, userId int) (*ResultResponseType, error) {
+	return n.RemoveCollaboratorContext(context.Background(), id, userId)
+}
+
+// RemoveCollaborator honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveCollaboratorContext(ctx context.Context, id int, userId int) (*ResultResponseType, error) {
+	route := sharingRoute(id) + strconv.Itoa(userId)
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "DELETE", route, nil))
+}
+
+// Accept an invitation to a shared collection
+/*
+	[IN] form:
+		token string
+
+	[OUT] form:
+		result bool
+		roleJoined string
+*/
+func (n *RaindropIOClient) JoinCollection(id int, token string) (*JoinResponseType, error) {
+	return n.JoinCollectionContext(context.Background(), id, token)
+}
+
+// JoinCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) JoinCollectionContext(ctx context.Context, id int, token string) (*JoinResponseType, error) {
+	route := ROUTE_COLLECTION + strconv.Itoa(id) + "/" + ROUTE_JOIN
+	in := JoinBody{Token: token}
+	return decodeResponse[JoinResponseType](n.ExecuteContext(ctx, "POST", route, in))
+}
+
+// Search for collection covers and icons. An empty text lists featured ones.
+/*
+	[OUT] form:
+		result bool
+		items []Object
+			title string
+			icons []{png string}
+*/
+func (n *RaindropIOClient) SearchCovers(text string) (*ItemsResponseType[CoverGroupType], error) {
+	return n.SearchCoversContext(context.Background(), text)
+}
+
+// SearchCovers honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) SearchCoversContext(ctx context.Context, text string) (*ItemsResponseType[CoverGroupType], error) {
+	route := ROUTE_COLLECTIONS + ROUTE_COVERS + url.PathEscape(text)
+	return decodeResponse[ItemsResponseType[CoverGroupType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+func sharingRoute(id int) string {
+	return ROUTE_COLLECTION + strconv.Itoa(id) + "/" + ROUTE_SHARING
 }
 
 // -------------------------------------------------------------------------
@@ -271,20 +440,14 @@ func (n *RaindropIOClient) RemoveMultipleCollections(in IDList) OperationRespons
 		result bool
 		item RaindropType
 */
-func (n *RaindropIOClient) GetRaindrop(id int) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROP
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("GET", n.Baseurl+route+strconv.Itoa(id), nil)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	req.Header.Add("Authorization", n.Bearer)
+func (n *RaindropIOClient) GetRaindrop(id int) (*ItemResponseType[RaindropType], error) {
+	return n.GetRaindropContext(context.Background(), id)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// GetRaindrop honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetRaindropContext(ctx context.Context, id int) (*ItemResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROP + strconv.Itoa(id)
+	return decodeResponse[ItemResponseType[RaindropType]](n.ExecuteContext(ctx, "GET", route, nil))
 }
 
 // Create raindrop
@@ -302,33 +465,21 @@ func (n *RaindropIOClient) GetRaindrop(id int) OperationResponseType {
 		excerpt string
 		title string
 		link string
-		highlights []Highlight(?)
+		highlights []HighlightType
 		reminder
 
 	[OUT] form:
 		result bool
 		item RaindropType
 */
-func (n *RaindropIOClient) CreateRaindrop(in RaindropType) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROP
-
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(in)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("POST", n.Baseurl+route, strings.NewReader(string(parsed)))
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Set("Authorization", n.Bearer)
-	req.Header.Set("Content-Type", "application/json")
+func (n *RaindropIOClient) CreateRaindrop(in RaindropType) (*ItemResponseType[RaindropType], error) {
+	return n.CreateRaindropContext(context.Background(), in)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// CreateRaindrop honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) CreateRaindropContext(ctx context.Context, in RaindropType) (*ItemResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROP
+	return decodeResponse[ItemResponseType[RaindropType]](n.ExecuteContext(ctx, "POST", route, in))
 }
 
 // Update raindrop
@@ -353,26 +504,38 @@ func (n *RaindropIOClient) CreateRaindrop(in RaindropType) OperationResponseType
 		result bool
 		item RaindropType
 */
-func (n *RaindropIOClient) UpdateRaindrop(id int, in RaindropType) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROP
+func (n *RaindropIOClient) UpdateRaindrop(id int, in RaindropType) (*ItemResponseType[RaindropType], error) {
+	return n.UpdateRaindropContext(context.Background(), id, in)
+}
 
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(in)
-	if opRes.err != nil {
-		return opRes
-	}
+// UpdateRaindrop honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UpdateRaindropContext(ctx context.Context, id int, in RaindropType) (*ItemResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROP + strconv.Itoa(id)
+	return decodeResponse[ItemResponseType[RaindropType]](n.ExecuteContext(ctx, "PUT", route, in))
+}
 
-	var req *http.Request
-	req, opRes.err = http.NewRequest("PUT", n.Baseurl+route+strconv.Itoa(id), strings.NewReader(string(parsed)))
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Set("Authorization", n.Bearer)
-	req.Header.Set("Content-Type", "application/json")
+// Update only the fields set in the patch
+/*
+	[IN] form:
+		title string
+		note string
+		tags []string
+		important bool
+		collection {$id int}
+		reminder {date string}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+	[OUT] form:
+		result bool
+		item RaindropType
+*/
+func (n *RaindropIOClient) PatchRaindrop(id int, in RaindropPatchType) (*ItemResponseType[RaindropType], error) {
+	return n.PatchRaindropContext(context.Background(), id, in)
+}
+
+// PatchRaindrop honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) PatchRaindropContext(ctx context.Context, id int, in RaindropPatchType) (*ItemResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROP + strconv.Itoa(id)
+	return decodeResponse[ItemResponseType[RaindropType]](n.ExecuteContext(ctx, "PUT", route, in))
 }
 
 // Remove raindrop
@@ -380,20 +543,14 @@ func (n *RaindropIOClient) UpdateRaindrop(id int, in RaindropType) OperationResp
 	[OUT] form:
 		result bool
 */
-func (n *RaindropIOClient) RemoveRaindrop(id int) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROP
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("DELETE", n.Baseurl+route+strconv.Itoa(id), nil)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	req.Header.Add("Authorization", n.Bearer)
+func (n *RaindropIOClient) RemoveRaindrop(id int) (*ResultResponseType, error) {
+	return n.RemoveRaindropContext(context.Background(), id)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// RemoveRaindrop honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveRaindropContext(ctx context.Context, id int) (*ResultResponseType, error) {
+	route := ROUTE_RAINDROP + strconv.Itoa(id)
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "DELETE", route, nil))
 }
 
 // Suggest collection and tags for new bookmark
@@ -404,29 +561,17 @@ func (n *RaindropIOClient) RemoveRaindrop(id int) OperationResponseType {
 			collections []{$id int}
 		tags []string
 */
-func (n *RaindropIOClient) NewBookmarkSuggestions(in LinkBody) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROP + ROUTE_SUGGEST
-
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(in)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("POST", n.Baseurl+route, strings.NewReader(string(parsed)))
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Set("Authorization", n.Bearer)
-	req.Header.Set("Content-Type", "application/json")
+func (n *RaindropIOClient) NewBookmarkSuggestions(in LinkBody) (*ItemResponseType[SuggestionsType], error) {
+	return n.NewBookmarkSuggestionsContext(context.Background(), in)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// NewBookmarkSuggestions honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) NewBookmarkSuggestionsContext(ctx context.Context, in LinkBody) (*ItemResponseType[SuggestionsType], error) {
+	route := ROUTE_RAINDROP + ROUTE_SUGGEST
+	return decodeResponse[ItemResponseType[SuggestionsType]](n.ExecuteContext(ctx, "POST", route, in))
 }
 
-// Suggest collection and tags for new bookmark
+// Suggest collection and tags for existing bookmark
 /*
 	[OUT] form:
 		result bool
@@ -434,20 +579,14 @@ func (n *RaindropIOClient) NewBookmarkSuggestions(in LinkBody) OperationResponse
 			collections []{$id int}
 		tags []string
 */
-func (n *RaindropIOClient) ExistingBookmarkSuggestions(id int) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROP
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("GET", n.Baseurl+route+strconv.Itoa(id)+ROUTE_SUGGEST, nil)
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Set("Authorization", n.Bearer)
-	req.Header.Set("Content-Type", "application/json")
+func (n *RaindropIOClient) ExistingBookmarkSuggestions(id int) (*ItemResponseType[SuggestionsType], error) {
+	return n.ExistingBookmarkSuggestionsContext(context.Background(), id)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// ExistingBookmarkSuggestions honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) ExistingBookmarkSuggestionsContext(ctx context.Context, id int) (*ItemResponseType[SuggestionsType], error) {
+	route := ROUTE_RAINDROP + strconv.Itoa(id) + "/" + ROUTE_SUGGEST
+	return decodeResponse[ItemResponseType[SuggestionsType]](n.ExecuteContext(ctx, "GET", route, nil))
 }
 
 // Get Raindrops
@@ -455,23 +594,18 @@ func (n *RaindropIOClient) ExistingBookmarkSuggestions(id int) OperationResponse
 	[OUT] form:
 		result bool
 		items []RaindropType
+		count int
+		collectionId int
 */
-func (n *RaindropIOClient) GetRaindrops(collectionId int, filter FilterType) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROPS
+func (n *RaindropIOClient) GetRaindrops(collectionId int, filter FilterType) (*ItemsResponseType[RaindropType], error) {
+	return n.GetRaindropsContext(context.Background(), collectionId, filter)
+}
 
-	parsedFilter := CreateFilterQuery(&filter)
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("GET", n.Baseurl+route+strconv.Itoa(collectionId)+parsedFilter, nil)
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Set("Authorization", n.Bearer)
-
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
-}
+// GetRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetRaindropsContext(ctx context.Context, collectionId int, filter FilterType) (*ItemsResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROPS + strconv.Itoa(collectionId) + CreateFilterQuery(&filter)
+	return decodeResponse[ItemsResponseType[RaindropType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
 
 // Create many raindrops
 /*
@@ -482,26 +616,14 @@ func (n *RaindropIOClient) GetRaindrops(collectionId int, filter FilterType) Ope
 		result bool
 		items []RaindropType
 */
-func (n *RaindropIOClient) CreateManyRaindrops(in ListBody) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROPS
-
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(in)
-	if opRes.err != nil {
-		return opRes
-	}
-
-	var req *http.Request
-	req, opRes.err = http.NewRequest("POST", n.Baseurl+route, strings.NewReader(string(parsed)))
-	if opRes.err != nil {
-		return opRes
-	}
-	req.Header.Set("Authorization", n.Bearer)
-	req.Header.Set("Content-Type", "application/json")
+func (n *RaindropIOClient) CreateManyRaindrops(in ListBody) (*ItemsResponseType[RaindropType], error) {
+	return n.CreateManyRaindropsContext(context.Background(), in)
+}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
-	return opRes
+// CreateManyRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) CreateManyRaindropsContext(ctx context.Context, in ListBody) (*ItemsResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROPS
+	return decodeResponse[ItemsResponseType[RaindropType]](n.ExecuteContext(ctx, "POST", route, in))
 }
 
 // Update many raindrops
@@ -513,74 +635,608 @@ func (n *RaindropIOClient) CreateManyRaindrops(in ListBody) OperationResponseTyp
 		media []string
 		cover string
 		collection CollectionParentType
+
+	[OUT] form:
+		result bool
+		modified int
 */
-func (n *RaindropIOClient) UpdateManyRaindrops(collectionId int, updates RaindropUpdateType) OperationResponseType {
-	opRes := OperationResponseType{response: nil, err: nil}
-	route := ROUTE_RAINDROPS
+func (n *RaindropIOClient) UpdateManyRaindrops(collectionId int, updates RaindropUpdateType) (*ModifiedResponseType, error) {
+	return n.UpdateManyRaindropsContext(context.Background(), collectionId, updates)
+}
 
-	var parsed []byte
-	parsed, opRes.err = json.Marshal(updates)
-	if opRes.err != nil {
-		return opRes
+// UpdateManyRaindrops honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UpdateManyRaindropsContext(ctx context.Context, collectionId int, updates RaindropUpdateType) (*ModifiedResponseType, error) {
+	route := ROUTE_RAINDROPS + strconv.Itoa(collectionId)
+	return decodeResponse[ModifiedResponseType](n.ExecuteContext(ctx, "PUT", route, updates))
+}
+
+// -------------------------------------------------------------------------
+// Tags methods
+// -------------------------------------------------------------------------
+
+// Get tags of every collection
+/*
+	[OUT] form:
+		result bool
+		items []Object
+			_id string
+			count int
+*/
+func (n *RaindropIOClient) GetTags() (*ItemsResponseType[TagType], error) {
+	return n.GetTagsContext(context.Background())
+}
+
+// GetTags honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetTagsContext(ctx context.Context) (*ItemsResponseType[TagType], error) {
+	route := ROUTE_TAGS
+	return decodeResponse[ItemsResponseType[TagType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Get tags used in a single collection
+/*
+	[OUT] form:
+		result bool
+		items []Object
+			_id string
+			count int
+*/
+func (n *RaindropIOClient) GetTagsInCollection(collectionId int) (*ItemsResponseType[TagType], error) {
+	return n.GetTagsInCollectionContext(context.Background(), collectionId)
+}
+
+// GetTagsInCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetTagsInCollectionContext(ctx context.Context, collectionId int) (*ItemsResponseType[TagType], error) {
+	route := ROUTE_TAGS + strconv.Itoa(collectionId)
+	return decodeResponse[ItemsResponseType[TagType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Rename a tag. A collectionId of 0 renames it everywhere.
+/*
+	[IN] form:
+		replace string
+		tags []string
+
+	[OUT] form:
+		result bool
+*/
+func (n *RaindropIOClient) RenameTag(collectionId int, tag string, replace string) (*ResultResponseType, error) {
+	return n.RenameTagContext(context.Background(), collectionId, tag, replace)
+}
+
+// RenameTag honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RenameTagContext(ctx context.Context, collectionId int, tag string, replace string) (*ResultResponseType, error) {
+	return n.MergeTagsContext(ctx, collectionId, []string{tag}, replace)
+}
+
+// Merge several tags into one. A collectionId of 0 merges them everywhere.
+/*
+	[IN] form:
+		replace string
+		tags []string
+
+	[OUT] form:
+		result bool
+*/
+func (n *RaindropIOClient) MergeTags(collectionId int, tags []string, replace string) (*ResultResponseType, error) {
+	return n.MergeTagsContext(context.Background(), collectionId, tags, replace)
+}
+
+// MergeTags honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) MergeTagsContext(ctx context.Context, collectionId int, tags []string, replace string) (*ResultResponseType, error) {
+	route := tagsRoute(collectionId)
+	in := TagsBody{Replace: replace, Tags: tags}
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "PUT", route, in))
+}
+
+// Remove tags from every raindrop. A collectionId of 0 removes them everywhere.
+/*
+	[IN] form:
+		tags []string
+
+	[OUT] form:
+		result bool
+*/
+func (n *RaindropIOClient) RemoveTags(collectionId int, tags []string) (*ResultResponseType, error) {
+	return n.RemoveTagsContext(context.Background(), collectionId, tags)
+}
+
+// RemoveTags honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveTagsContext(ctx context.Context, collectionId int, tags []string) (*ResultResponseType, error) {
+	route := tagsRoute(collectionId)
+	in := TagsBody{Tags: tags}
+	return decodeResponse[ResultResponseType](n.ExecuteContext(ctx, "DELETE", route, in))
+}
+
+// The tags routes treat a missing collection id as "all collections"
+func tagsRoute(collectionId int) string {
+	if collectionId == 0 {
+		return ROUTE_TAGS
 	}
+	return ROUTE_TAGS + strconv.Itoa(collectionId)
+}
+
+// -------------------------------------------------------------------------
+// Highlights methods
+// -------------------------------------------------------------------------
+
+// Get highlights across every collection
+/*
+	[OUT] form:
+		result bool
+		items []HighlightType
+*/
+func (n *RaindropIOClient) GetAllHighlights(page int, perPage int) (*ItemsResponseType[HighlightType], error) {
+	return n.GetAllHighlightsContext(context.Background(), page, perPage)
+}
+
+// GetAllHighlights honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetAllHighlightsContext(ctx context.Context, page int, perPage int) (*ItemsResponseType[HighlightType], error) {
+	route := ROUTE_HIGHLIGHTS + pageQuery(page, perPage)
+	return decodeResponse[ItemsResponseType[HighlightType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Get highlights of raindrops in a collection
+/*
+	[OUT] form:
+		result bool
+		items []HighlightType
+*/
+func (n *RaindropIOClient) GetHighlightsInCollection(collectionId int, page int, perPage int) (*ItemsResponseType[HighlightType], error) {
+	return n.GetHighlightsInCollectionContext(context.Background(), collectionId, page, perPage)
+}
+
+// GetHighlightsInCollection honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetHighlightsInCollectionContext(ctx context.Context, collectionId int, page int, perPage int) (*ItemsResponseType[HighlightType], error) {
+	route := ROUTE_HIGHLIGHTS + strconv.Itoa(collectionId) + pageQuery(page, perPage)
+	return decodeResponse[ItemsResponseType[HighlightType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Get highlights of a single raindrop
+/*
+	[OUT] form:
+		result bool
+		items []HighlightType
+*/
+func (n *RaindropIOClient) GetHighlightsOfRaindrop(id int) (*ItemsResponseType[HighlightType], error) {
+	return n.GetHighlightsOfRaindropContext(context.Background(), id)
+}
+
+// GetHighlightsOfRaindrop honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetHighlightsOfRaindropContext(ctx context.Context, id int) (*ItemsResponseType[HighlightType], error) {
+	res, err := n.GetRaindropContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &ItemsResponseType[HighlightType]{
+		ResultResponseType: res.ResultResponseType,
+		Items:              res.Item.Highlights,
+		Count:              int64(len(res.Item.Highlights)),
+	}, nil
+}
+
+// Add highlights to a raindrop
+/*
+	[IN] form:
+		highlights []Object
+			text string
+			color string
+			note string
+
+	[OUT] form:
+		result bool
+		item RaindropType
+*/
+func (n *RaindropIOClient) AddHighlights(raindropId int, highlights []HighlightType) (*ItemResponseType[RaindropType], error) {
+	return n.AddHighlightsContext(context.Background(), raindropId, highlights)
+}
+
+// AddHighlights honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) AddHighlightsContext(ctx context.Context, raindropId int, highlights []HighlightType) (*ItemResponseType[RaindropType], error) {
+	in := HighlightsBody{}
+	for _, h := range highlights {
+		text := h.Text
+		in.Highlights = append(in.Highlights, HighlightEditType{Text: &text, Color: h.Color, Note: h.Note, Tags: h.Tags})
+	}
+	return n.editHighlights(ctx, raindropId, in)
+}
+
+// Update the note or color of existing highlights, matched by _id
+/*
+	[IN] form:
+		highlights []Object
+			_id string
+			color string
+			note string
+
+	[OUT] form:
+		result bool
+		item RaindropType
+*/
+func (n *RaindropIOClient) UpdateHighlights(raindropId int, highlights []HighlightType) (*ItemResponseType[RaindropType], error) {
+	return n.UpdateHighlightsContext(context.Background(), raindropId, highlights)
+}
+
+// UpdateHighlights honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UpdateHighlightsContext(ctx context.Context, raindropId int, highlights []HighlightType) (*ItemResponseType[RaindropType], error) {
+	in := HighlightsBody{}
+	for _, h := range highlights {
+		in.Highlights = append(in.Highlights, HighlightEditType{Id: h.Id, Color: h.Color, Note: h.Note, Tags: h.Tags})
+	}
+	return n.editHighlights(ctx, raindropId, in)
+}
+
+// Remove highlights from a raindrop by _id
+/*
+	[IN] form:
+		highlights []Object
+			_id string
+			text "" (empty text removes the highlight)
+
+	[OUT] form:
+		result bool
+		item RaindropType
+*/
+func (n *RaindropIOClient) RemoveHighlights(raindropId int, ids []string) (*ItemResponseType[RaindropType], error) {
+	return n.RemoveHighlightsContext(context.Background(), raindropId, ids)
+}
+
+// RemoveHighlights honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) RemoveHighlightsContext(ctx context.Context, raindropId int, ids []string) (*ItemResponseType[RaindropType], error) {
+	in := HighlightsBody{}
+	empty := ""
+	for _, id := range ids {
+		in.Highlights = append(in.Highlights, HighlightEditType{Id: id, Text: &empty})
+	}
+	return n.editHighlights(ctx, raindropId, in)
+}
+
+// Highlights are edited through the raindrop they belong to
+func (n *RaindropIOClient) editHighlights(ctx context.Context, raindropId int, in HighlightsBody) (*ItemResponseType[RaindropType], error) {
+	route := ROUTE_RAINDROP + strconv.Itoa(raindropId)
+	return decodeResponse[ItemResponseType[RaindropType]](n.ExecuteContext(ctx, "PUT", route, in))
+}
+
+// Build a page/perpage query string, leaving out unset values
+func pageQuery(page int, perPage int) string {
+	return CreateFilterQuery(&FilterType{Page: page, PerPage: perPage})
+}
+
+// -------------------------------------------------------------------------
+// User methods
+// -------------------------------------------------------------------------
+
+// Get the user the bearer token belongs to
+/*
+	[OUT] form:
+		result bool
+		user UserProfileType
+*/
+func (n *RaindropIOClient) GetUser() (*UserResponseType, error) {
+	return n.GetUserContext(context.Background())
+}
+
+// GetUser honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetUserContext(ctx context.Context) (*UserResponseType, error) {
+	route := ROUTE_USER
+	return decodeResponse[UserResponseType](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Get the public profile of a user by name
+/*
+	[OUT] form:
+		result bool
+		user UserProfileType
+*/
+func (n *RaindropIOClient) GetUserByName(name string) (*UserResponseType, error) {
+	return n.GetUserByNameContext(context.Background(), name)
+}
+
+// GetUserByName honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetUserByNameContext(ctx context.Context, name string) (*UserResponseType, error) {
+	route := ROUTE_USER + url.PathEscape(name)
+	return decodeResponse[UserResponseType](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Update the authenticated user's config
+/*
+	[IN] form:
+		config UserConfigType
+
+	[OUT] form:
+		result bool
+		user UserProfileType
+*/
+func (n *RaindropIOClient) UpdateUserConfig(config UserConfigType) (*UserResponseType, error) {
+	return n.UpdateUserConfigContext(context.Background(), config)
+}
+
+// UpdateUserConfig honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) UpdateUserConfigContext(ctx context.Context, config UserConfigType) (*UserResponseType, error) {
+	route := ROUTE_USER
+	in := UserProfileType{Config: &config}
+	return decodeResponse[UserResponseType](n.ExecuteContext(ctx, "PUT", route, in))
+}
+
+// Get raindrop counts of the system collections (all, unsorted, trash)
+/*
+	[OUT] form:
+		result bool
+		items []{_id int, count int}
+		meta Object
+			_id int
+			pro bool
+			changedBookmarksDate string
+			duplicates {count int}
+			broken {count int}
+*/
+func (n *RaindropIOClient) GetUserStats() (*UserStatsResponseType, error) {
+	return n.GetUserStatsContext(context.Background())
+}
+
+// GetUserStats honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetUserStatsContext(ctx context.Context) (*UserStatsResponseType, error) {
+	route := ROUTE_USER + ROUTE_STATS
+	return decodeResponse[UserStatsResponseType](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Get broken, duplicate, important and untagged counts of a collection
+/*
+	[OUT] form:
+		result bool
+		broken {count int}
+		duplicates {count int}
+		important {count int}
+		notag {count int}
+		tags []{_id string, count int}
+		types []{_id string, count int}
+*/
+func (n *RaindropIOClient) GetCollectionStats(collectionId int) (*CollectionStatsType, error) {
+	return n.GetCollectionStatsContext(context.Background(), collectionId)
+}
+
+// GetCollectionStats honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) GetCollectionStatsContext(ctx context.Context, collectionId int) (*CollectionStatsType, error) {
+	route := ROUTE_FILTERS + strconv.Itoa(collectionId)
+	return decodeResponse[CollectionStatsType](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// -------------------------------------------------------------------------
+// Import methods
+// -------------------------------------------------------------------------
+
+// Parse a url into the metadata a new raindrop would get
+/*
+	[OUT] form:
+		result bool
+		error string (when the page could not be parsed)
+		item Object
+			title string
+			excerpt string
+			type string
+			meta {canonical string, site string, tags []string}
+			media []{link string, type string}
+*/
+func (n *RaindropIOClient) ParseURL(link string) (*ItemResponseType[ParsedURLType], error) {
+	return n.ParseURLContext(context.Background(), link)
+}
+
+// ParseURL honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) ParseURLContext(ctx context.Context, link string) (*ItemResponseType[ParsedURLType], error) {
+	route := ROUTE_IMPORT + ROUTE_URL + ROUTE_PARSE + "?url=" + url.QueryEscape(link)
+	return decodeResponse[ItemResponseType[ParsedURLType]](n.ExecuteContext(ctx, "GET", route, nil))
+}
+
+// Check which urls are already saved
+/*
+	[IN] form:
+		urls []string
+
+	[OUT] form:
+		result bool
+		ids []int
+		duplicates []{_id int, link string}
+*/
+func (n *RaindropIOClient) CheckURLsExist(links []string) (*URLsExistResponseType, error) {
+	return n.CheckURLsExistContext(context.Background(), links)
+}
+
+// CheckURLsExist honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) CheckURLsExistContext(ctx context.Context, links []string) (*URLsExistResponseType, error) {
+	route := ROUTE_IMPORT + ROUTE_URL + ROUTE_EXISTS
+	in := URLsBody{Urls: links}
+	return decodeResponse[URLsExistResponseType](n.ExecuteContext(ctx, "POST", route, in))
+}
+
+// Parse a Netscape HTML bookmarks file. Nothing is saved; create the
+// returned bookmarks with CreateManyRaindrops.
+/*
+	[IN] form (multipart):
+		import binary
+
+	[OUT] form:
+		result bool
+		items []Object
+			title string
+			folders []Object (recursive)
+			bookmarks []RaindropType
+*/
+func (n *RaindropIOClient) ImportBookmarkFile(name string, r io.Reader) (*ItemsResponseType[ImportFolderType], error) {
+	return n.ImportBookmarkFileContext(context.Background(), name, r)
+}
+
+// ImportBookmarkFile honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) ImportBookmarkFileContext(ctx context.Context, name string, r io.Reader) (*ItemsResponseType[ImportFolderType], error) {
+	route := ROUTE_IMPORT + ROUTE_FILE
+	return decodeResponse[ItemsResponseType[ImportFolderType]](n.executeMultipart(ctx, "POST", route, "import", name, r, nil))
+}
+
+// -------------------------------------------------------------------------
+// Request helpers
+// -------------------------------------------------------------------------
+
+// Send a request to a route relative to the client's base url.
+// `in` is encoded as the JSON body when it is not nil. This is the raw
+// escape hatch used by every wrapper above; read the result with
+// ExecuteOnResponse or decode it into a typed response.
+func (n *RaindropIOClient) Execute(method string, route string, in any) OperationResponseType {
+	return n.ExecuteContext(context.Background(), method, route, in)
+}
+
+// Execute honoring ctx cancellation and deadlines
+func (n *RaindropIOClient) ExecuteContext(ctx context.Context, method string, route string, in any) OperationResponseType {
+	if in == nil {
+		return n.executeBody(ctx, method, route, "", nil)
+	}
+
+	parsed, err := json.Marshal(in)
+	if err != nil {
+		return OperationResponseType{response: nil, err: err, method: method, route: route}
+	}
+	return n.executeBody(ctx, method, route, "application/json", bytes.NewReader(parsed))
+}
+
+// Send a request with an already encoded body. Bodies that cannot be
+// rewound (anything but bytes, strings readers and buffers) are never
+// retried.
+func (n *RaindropIOClient) executeBody(ctx context.Context, method string, route string, contentType string, body io.Reader) OperationResponseType {
+	opRes := OperationResponseType{response: nil, err: nil, method: method, route: route}
 
 	var req *http.Request
-	req, opRes.err = http.NewRequest("PUT", n.Baseurl+route+strconv.Itoa(collectionId), strings.NewReader(string(parsed)))
+	req, opRes.err = http.NewRequestWithContext(ctx, method, n.Baseurl+route, body)
 	if opRes.err != nil {
 		return opRes
 	}
-	req.Header.Set("Authorization", n.Bearer)
-	req.Header.Set("Content-Type", "application/json")
+	var token *TokenType
+	if n.Tokens != nil {
+		token, opRes.err = n.Tokens.Token(ctx)
+		if opRes.err != nil {
+			return opRes
+		}
+		req.Header.Set("Authorization", token.Header())
+	} else {
+		req.Header.Set("Authorization", n.Bearer)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if n.UserAgent != "" {
+		req.Header.Set("User-Agent", n.UserAgent)
+	}
 
-	opRes.response, opRes.err = n.Handle.Do(req)
+	opRes.response, opRes.err = n.do(req)
+	if opRes.err == nil && opRes.response.StatusCode == http.StatusUnauthorized && token != nil {
+		if retry, ok := n.reauthorize(ctx, req, token); ok {
+			io.Copy(io.Discard, opRes.response.Body)
+			opRes.response.Body.Close()
+			opRes.response, opRes.err = n.do(retry)
+		}
+	}
 	return opRes
 }
 
-// Build a filter query string
-func CreateFilterQuery(filter *FilterType) string {
-	queryString := "?"
+// After a 401, drop token and prepare req again with a fresh one. Fails
+// when the source cannot refresh early or the body cannot be resent.
+func (n *RaindropIOClient) reauthorize(ctx context.Context, req *http.Request, token *TokenType) (*http.Request, bool) {
+	source, ok := n.Tokens.(InvalidatingTokenSource)
+	if !ok {
+		return nil, false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return nil, false
+	}
 
-	if filter != nil {
-		var q []string
-		if filter.Sort != "" {
-			q = append(q, "sort="+filter.Sort)
-		}
-		if filter.Page >= 0 {
-			q = append(q, "page="+strconv.Itoa(filter.Page))
-		}
-		if filter.PerPage >= 0 {
-			q = append(q, "perpage="+strconv.Itoa(filter.PerPage))
-		}
-		if filter.Search != "" {
-			q = append(q, "search="+filter.Search)
-		}
+	source.Invalidate(token)
+	fresh, err := source.Token(ctx)
+	if err != nil || fresh.AccessToken == token.AccessToken {
+		return nil, false
+	}
 
-		// build query string
-		for i, s := range q {
-			if i > 0 {
-				queryString += "&"
-			}
-			queryString += s
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, false
 		}
+		retry.Body = body
 	}
+	retry.Header.Set("Authorization", fresh.Header())
+	return retry, true
+}
 
-	return queryString
+// Decode the JSON body of an operation into T.
+func decodeResponse[T any](opRes OperationResponseType) (*T, error) {
+	body, err := opRes.readBody()
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(T)
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-// Add callback for output of an operation.
-// JSON form located above wrapper methods.
-func (opRes *OperationResponseType) ExecuteOnResponse(callback func(jsonResponse string)) {
-	// if errored, panic
+// Read the whole body of an operation, turning transport failures,
+// HTTP error statuses and `result: false` bodies into errors.
+func (opRes *OperationResponseType) readBody() ([]byte, error) {
 	if opRes.err != nil {
-		panic(opRes.err)
+		return nil, opRes.err
 	}
-
 	defer opRes.response.Body.Close()
 
 	body, err := io.ReadAll(opRes.response.Body)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	if apiErr := newAPIError(opRes, body); apiErr != nil {
+		return nil, apiErr
+	}
+	return body, nil
+}
+
+// Build a filter query string, URL-encoded and leaving out unset fields.
+// Empty when nothing is set. CollectionId and Ids are not query
+// parameters; see FindRaindrops.
+func CreateFilterQuery(filter *FilterType) string {
+	if filter == nil {
+		return ""
+	}
+
+	q := url.Values{}
+	if filter.Sort != "" {
+		q.Set("sort", filter.Sort)
+	}
+	if filter.Page > 0 {
+		q.Set("page", strconv.Itoa(filter.Page))
+	}
+	if filter.PerPage > 0 {
+		q.Set("perpage", strconv.Itoa(filter.PerPage))
+	}
+	if filter.Search != "" {
+		q.Set("search", filter.Search)
+	}
+	if filter.Nested {
+		q.Set("nested", "true")
+	}
+
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+// Add callback for output of an operation.
+// JSON form located above wrapper methods. The callback is only run
+// when the request succeeded; otherwise the error is returned, as an
+// *APIError when the API rejected the request.
+func (opRes *OperationResponseType) ExecuteOnResponse(callback func(jsonResponse string)) error {
+	body, err := opRes.readBody()
+	if err != nil {
+		return err
 	}
 
 	callback(string(body))
+	return nil
 }
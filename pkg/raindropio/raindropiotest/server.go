@@ -0,0 +1,972 @@
+// Package raindropiotest provides an in-memory Raindrop.io API for tests
+// that must not touch the network.
+package raindropiotest
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/GearTech0/nimbus/pkg/raindropio"
+)
+
+const DEFAULT_PER_PAGE int = 25
+
+// A fake of the collections, raindrops, tags, upload, import, export,
+// backup and user routes, backed by maps. The zero Token accepts any
+// Authorization header.
+type Server struct {
+	*httptest.Server
+	Token string
+
+	mu          sync.Mutex
+	nextId      int64
+	collections map[int64]*collectionEntry
+	raindrops   map[int64]*raindropEntry
+	backups     []BackupType
+	requests    []string
+}
+
+type collectionEntry struct {
+	id     int64
+	parent int64
+	data   CollectionType
+}
+
+type raindropEntry struct {
+	id         int64
+	collection int64
+	data       RaindropType
+}
+
+// Start a server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		nextId:      1000,
+		collections: map[int64]*collectionEntry{},
+		raindrops:   map[int64]*raindropEntry{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// A client pointed at the server, without retries or rate limiting
+func (s *Server) Client() *RaindropIOClient {
+	client, err := NewClient(
+		WithBaseURL(s.URL),
+		WithBearerToken(s.Token),
+		WithTransport(s.Server.Client().Transport),
+		WithRetryPolicy(nil),
+		WithRateLimiter(nil),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// Seed a collection and return its id
+func (s *Server) AddCollection(in CollectionType) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addCollection(in)
+}
+
+// Seed a raindrop into a collection and return its id
+func (s *Server) AddRaindrop(collectionId int64, in RaindropType) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addRaindrop(collectionId, in)
+}
+
+// Look a raindrop up directly, bypassing the API
+func (s *Server) Raindrop(id int64) (RaindropType, int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.raindrops[id]
+	if !ok {
+		return RaindropType{}, 0, false
+	}
+	return entry.data, entry.collection, true
+}
+
+// Every request served so far, as "METHOD /path?query"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) addCollection(in CollectionType) int64 {
+	s.nextId++
+	entry := &collectionEntry{id: s.nextId, data: in}
+	if in.Parent != nil {
+		entry.parent = in.Parent.Id
+	}
+	entry.data.Id = entry.id
+	if entry.data.Created == "" {
+		entry.data.Created = now()
+	}
+	entry.data.LastUpdate = entry.data.Created
+	s.collections[entry.id] = entry
+	return entry.id
+}
+
+func (s *Server) addRaindrop(collectionId int64, in RaindropType) int64 {
+	s.nextId++
+	entry := &raindropEntry{id: s.nextId, collection: collectionId, data: in}
+	if entry.data.Created == "" {
+		entry.data.Created = now()
+	}
+	if entry.data.LastUpdate == "" {
+		entry.data.LastUpdate = entry.data.Created
+	}
+	entry.data.Collection = &CollectionParentType{Id: collectionId}
+	s.raindrops[entry.id] = entry
+	return entry.id
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// ------------------------------------------------------------------------
+// Routing
+// ------------------------------------------------------------------------
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid token")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	route := r.Method + " " + parts[0]
+	var id int64
+	hasId := false
+	if len(parts) > 1 {
+		parsed, err := strconv.ParseInt(parts[1], 10, 64)
+		id, hasId = parsed, err == nil
+	}
+
+	switch {
+	case route == "GET collections" && len(parts) == 1:
+		s.getCollections(w, true)
+	case route == "GET collections" && parts[1] == "childrens":
+		s.getCollections(w, false)
+	case route == "GET collection" && hasId:
+		s.getCollection(w, id)
+	case route == "POST collection" && len(parts) == 1:
+		s.createCollection(w, r)
+	case route == "PUT collection" && hasId && len(parts) == 3 && parts[2] == "cover":
+		s.uploadCollectionCover(w, r, id)
+	case route == "PUT collection" && hasId:
+		s.updateCollection(w, r, id)
+	case route == "DELETE collection" && hasId:
+		s.removeCollection(w, id)
+
+	case route == "GET raindrop" && hasId:
+		s.getRaindrop(w, id)
+	case route == "POST raindrop" && len(parts) == 1:
+		s.createRaindrop(w, r)
+	case route == "PUT raindrop" && len(parts) == 2 && parts[1] == "file":
+		s.uploadFile(w, r)
+	case route == "PUT raindrop" && hasId && len(parts) == 3 && parts[2] == "cover":
+		s.uploadRaindropCover(w, r, id)
+	case route == "PUT raindrop" && hasId && len(parts) == 2:
+		s.updateRaindrop(w, r, id)
+	case route == "DELETE raindrop" && hasId:
+		s.removeRaindrop(w, id)
+
+	case route == "GET raindrops" && hasId && len(parts) == 3 && strings.HasPrefix(parts[2], "export."):
+		s.exportRaindrops(w, r, id, strings.TrimPrefix(parts[2], "export."))
+	case route == "GET raindrops" && hasId:
+		s.getRaindrops(w, r, id)
+	case route == "POST raindrops" && len(parts) == 1:
+		s.createRaindrops(w, r)
+	case route == "PUT raindrops" && hasId:
+		s.updateRaindrops(w, r, id)
+	case route == "DELETE raindrops" && hasId:
+		s.removeRaindrops(w, r, id)
+
+	case route == "GET tags":
+		s.getTags(w, id)
+	case route == "PUT tags":
+		s.mergeTags(w, r, id)
+	case route == "DELETE tags":
+		s.removeTags(w, r, id)
+
+	case route == "POST import" && len(parts) == 3 && parts[1] == "url" && parts[2] == "exists":
+		s.urlsExist(w, r)
+	case route == "POST import" && len(parts) == 2 && parts[1] == "file":
+		s.importFile(w, r)
+
+	case route == "GET backups" && len(parts) == 1:
+		writeJSON(w, map[string]any{"result": true, "items": append([]BackupType{}, s.backups...)})
+	case route == "GET backup" && len(parts) == 1:
+		s.generateBackup(w)
+	case route == "GET backup" && len(parts) == 2:
+		s.downloadBackup(w, parts[1])
+
+	case route == "GET user" && len(parts) == 1:
+		writeJSON(w, map[string]any{"result": true, "user": UserProfileType{Id: 1, FullName: "Test"}})
+
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "no route "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"result": false, "error": code, "errorMessage": message})
+}
+
+// Read the single file part of a multipart upload, with the form's plain
+// fields
+func readUpload(w http.ResponseWriter, r *http.Request, field string) (*uploadedFile, bool) {
+	form, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return nil, false
+	}
+
+	upload := &uploadedFile{fields: map[string]string{}}
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return nil, false
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return nil, false
+		}
+		if part.FileName() == "" {
+			upload.fields[part.FormName()] = string(content)
+			continue
+		}
+		if part.FormName() != field {
+			writeError(w, http.StatusBadRequest, "bad_request", "unexpected file field "+part.FormName())
+			return nil, false
+		}
+		upload.name = part.FileName()
+		upload.contentType = part.Header.Get("Content-Type")
+		upload.content = content
+	}
+	if upload.name == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "missing file field "+field)
+		return nil, false
+	}
+	return upload, true
+}
+
+type uploadedFile struct {
+	name        string
+	contentType string
+	content     []byte
+	fields      map[string]string
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, out any) bool {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return false
+	}
+	return true
+}
+
+// ------------------------------------------------------------------------
+// Collections
+// ------------------------------------------------------------------------
+
+func (s *Server) collectionJSON(entry *collectionEntry) CollectionType {
+	out := entry.data
+	out.Count = 0
+	for _, r := range s.raindrops {
+		if r.collection == entry.id {
+			out.Count++
+		}
+	}
+	return out
+}
+
+func (s *Server) getCollections(w http.ResponseWriter, root bool) {
+	items := []CollectionType{}
+	for _, id := range sortedKeys(s.collections) {
+		entry := s.collections[id]
+		if (entry.parent == 0) == root {
+			items = append(items, s.collectionJSON(entry))
+		}
+	}
+	writeJSON(w, map[string]any{"result": true, "items": items})
+}
+
+func (s *Server) getCollection(w http.ResponseWriter, id int64) {
+	entry, ok := s.collections[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "collection not found")
+		return
+	}
+	writeJSON(w, map[string]any{"result": true, "item": s.collectionJSON(entry)})
+}
+
+func (s *Server) createCollection(w http.ResponseWriter, r *http.Request) {
+	var in CollectionType
+	if !readJSON(w, r, &in) {
+		return
+	}
+	id := s.addCollection(in)
+	writeJSON(w, map[string]any{"result": true, "item": s.collectionJSON(s.collections[id])})
+}
+
+func (s *Server) updateCollection(w http.ResponseWriter, r *http.Request, id int64) {
+	entry, ok := s.collections[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "collection not found")
+		return
+	}
+
+	var in map[string]json.RawMessage
+	if !readJSON(w, r, &in) {
+		return
+	}
+	merged, _ := json.Marshal(entry.data)
+	var current map[string]json.RawMessage
+	json.Unmarshal(merged, &current)
+	for k, v := range in {
+		current[k] = v
+	}
+	merged, _ = json.Marshal(current)
+	json.Unmarshal(merged, &entry.data)
+	entry.data.Id = id
+	entry.parent = 0
+	if entry.data.Parent != nil {
+		entry.parent = entry.data.Parent.Id
+	}
+	entry.data.LastUpdate = now()
+
+	writeJSON(w, map[string]any{"result": true, "item": s.collectionJSON(entry)})
+}
+
+func (s *Server) removeCollection(w http.ResponseWriter, id int64) {
+	if id == int64(COLLECTION_TRASH) {
+		for rid, r := range s.raindrops {
+			if r.collection == id {
+				delete(s.raindrops, rid)
+			}
+		}
+		writeJSON(w, map[string]any{"result": true})
+		return
+	}
+
+	if _, ok := s.collections[id]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "collection not found")
+		return
+	}
+	delete(s.collections, id)
+	for _, r := range s.raindrops {
+		if r.collection == id {
+			r.collection = int64(COLLECTION_TRASH)
+			r.data.Collection = &CollectionParentType{Id: r.collection}
+		}
+	}
+	writeJSON(w, map[string]any{"result": true})
+}
+
+func (s *Server) uploadCollectionCover(w http.ResponseWriter, r *http.Request, id int64) {
+	entry, ok := s.collections[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "collection not found")
+		return
+	}
+	upload, ok := readUpload(w, r, "cover")
+	if !ok {
+		return
+	}
+	entry.data.Cover = []string{s.URL + "/covers/" + upload.name}
+	entry.data.LastUpdate = now()
+	writeJSON(w, map[string]any{"result": true, "item": s.collectionJSON(entry)})
+}
+
+// ------------------------------------------------------------------------
+// Raindrops
+// ------------------------------------------------------------------------
+
+// The raindrop as the API sends it, with its _id
+func raindropJSON(entry *raindropEntry) RaindropType {
+	out := entry.data
+	out.Id = entry.id
+	return out
+}
+
+func (s *Server) getRaindrop(w http.ResponseWriter, id int64) {
+	entry, ok := s.raindrops[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "raindrop not found")
+		return
+	}
+	writeJSON(w, map[string]any{"result": true, "item": raindropJSON(entry)})
+}
+
+func (s *Server) createRaindrop(w http.ResponseWriter, r *http.Request) {
+	var in RaindropType
+	if !readJSON(w, r, &in) {
+		return
+	}
+	collection := in.CollectionId()
+	if collection == 0 {
+		collection = int64(COLLECTION_UNSORTED)
+	}
+	id := s.addRaindrop(collection, in)
+	writeJSON(w, map[string]any{"result": true, "item": raindropJSON(s.raindrops[id])})
+}
+
+func (s *Server) updateRaindrop(w http.ResponseWriter, r *http.Request, id int64) {
+	entry, ok := s.raindrops[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "raindrop not found")
+		return
+	}
+
+	var in map[string]json.RawMessage
+	if !readJSON(w, r, &in) {
+		return
+	}
+	encoded, _ := json.Marshal(raindropJSON(entry))
+	var merged map[string]json.RawMessage
+	json.Unmarshal(encoded, &merged)
+	for k, v := range in {
+		merged[k] = v
+	}
+	encoded, _ = json.Marshal(merged)
+	var data RaindropType
+	json.Unmarshal(encoded, &data)
+	entry.data = data
+	if data.CollectionId() != 0 {
+		entry.collection = data.CollectionId()
+	}
+	entry.data.Collection = &CollectionParentType{Id: entry.collection}
+	entry.data.LastUpdate = now()
+
+	writeJSON(w, map[string]any{"result": true, "item": raindropJSON(entry)})
+}
+
+func (s *Server) removeRaindrop(w http.ResponseWriter, id int64) {
+	entry, ok := s.raindrops[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "raindrop not found")
+		return
+	}
+	s.trash(entry)
+	writeJSON(w, map[string]any{"result": true})
+}
+
+// Removing moves to the trash; removing from the trash deletes
+func (s *Server) trash(entry *raindropEntry) {
+	if entry.collection == int64(COLLECTION_TRASH) {
+		delete(s.raindrops, entry.id)
+		return
+	}
+	entry.collection = int64(COLLECTION_TRASH)
+	entry.data.Collection = &CollectionParentType{Id: entry.collection}
+}
+
+func (s *Server) getRaindrops(w http.ResponseWriter, r *http.Request, collectionId int64) {
+	q := r.URL.Query()
+	matched := s.match(collectionId, q.Get("search"), nil)
+	sortRaindrops(matched, q.Get("sort"))
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	perPage, _ := strconv.Atoi(q.Get("perpage"))
+	if perPage <= 0 {
+		perPage = DEFAULT_PER_PAGE
+	}
+
+	items := []RaindropType{}
+	for i := page * perPage; i < len(matched) && i < (page+1)*perPage; i++ {
+		items = append(items, raindropJSON(matched[i]))
+	}
+	writeJSON(w, map[string]any{
+		"result":       true,
+		"items":        items,
+		"count":        len(matched),
+		"collectionId": collectionId,
+	})
+}
+
+func (s *Server) createRaindrops(w http.ResponseWriter, r *http.Request) {
+	var in ListBody
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	items := []RaindropType{}
+	for _, raindrop := range in.Items {
+		collection := raindrop.CollectionId()
+		if collection == 0 {
+			collection = int64(COLLECTION_UNSORTED)
+		}
+		id := s.addRaindrop(collection, raindrop)
+		items = append(items, raindropJSON(s.raindrops[id]))
+	}
+	writeJSON(w, map[string]any{"result": true, "items": items})
+}
+
+func (s *Server) updateRaindrops(w http.ResponseWriter, r *http.Request, collectionId int64) {
+	var in map[string]json.RawMessage
+	if !readJSON(w, r, &in) {
+		return
+	}
+	var updates RaindropUpdateType
+	encoded, _ := json.Marshal(in)
+	json.Unmarshal(encoded, &updates)
+
+	matched := s.match(collectionId, r.URL.Query().Get("search"), updates.Ids)
+	for _, entry := range matched {
+		if _, ok := in["important"]; ok {
+			entry.data.Important = updates.Important
+		}
+		if _, ok := in["tags"]; ok {
+			// an empty list clears the tags, anything else is appended
+			if len(updates.Tags) == 0 {
+				entry.data.Tags = nil
+			}
+			for _, tag := range updates.Tags {
+				if !contains(entry.data.Tags, tag) {
+					entry.data.Tags = append(entry.data.Tags, tag)
+				}
+			}
+		}
+		if _, ok := in["cover"]; ok {
+			entry.data.Cover = updates.Cover
+		}
+		if updates.Collection != nil && updates.Collection.Id != 0 {
+			entry.collection = updates.Collection.Id
+			entry.data.Collection = &CollectionParentType{Id: entry.collection}
+		}
+		entry.data.LastUpdate = now()
+	}
+	writeJSON(w, map[string]any{"result": true, "modified": len(matched)})
+}
+
+func (s *Server) removeRaindrops(w http.ResponseWriter, r *http.Request, collectionId int64) {
+	var in IDList
+	if r.ContentLength != 0 && !readJSON(w, r, &in) {
+		return
+	}
+
+	matched := s.match(collectionId, r.URL.Query().Get("search"), in.Ids)
+	for _, entry := range matched {
+		s.trash(entry)
+	}
+	writeJSON(w, map[string]any{"result": true, "modified": len(matched)})
+}
+
+// Files become raindrops of the collection in the collectionId field,
+// typed from the content type the client sent
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request) {
+	upload, ok := readUpload(w, r, "file")
+	if !ok {
+		return
+	}
+
+	collection, err := strconv.ParseInt(upload.fields["collectionId"], 10, 64)
+	if err != nil || collection == 0 {
+		collection = int64(COLLECTION_UNSORTED)
+	}
+	kind := TYPE_DOCUMENT
+	if strings.HasPrefix(upload.contentType, "image/") {
+		kind = TYPE_IMAGE
+	}
+	id := s.addRaindrop(collection, RaindropType{
+		Title: upload.name,
+		Link:  s.URL + "/files/" + upload.name,
+		Type:  kind,
+		File:  &FileType{Name: upload.name, Size: int64(len(upload.content)), Type: upload.contentType},
+	})
+	writeJSON(w, map[string]any{"result": true, "item": raindropJSON(s.raindrops[id])})
+}
+
+func (s *Server) uploadRaindropCover(w http.ResponseWriter, r *http.Request, id int64) {
+	entry, ok := s.raindrops[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "raindrop not found")
+		return
+	}
+	upload, ok := readUpload(w, r, "cover")
+	if !ok {
+		return
+	}
+	entry.data.Cover = s.URL + "/covers/" + upload.name
+	entry.data.LastUpdate = now()
+	writeJSON(w, map[string]any{"result": true, "item": raindropJSON(entry)})
+}
+
+// ------------------------------------------------------------------------
+// Tags
+// ------------------------------------------------------------------------
+
+func (s *Server) getTags(w http.ResponseWriter, collectionId int64) {
+	counts := map[string]int64{}
+	for _, entry := range s.match(collectionId, "", nil) {
+		for _, tag := range entry.data.Tags {
+			counts[tag]++
+		}
+	}
+
+	items := []TagType{}
+	for tag, count := range counts {
+		items = append(items, TagType{Id: tag, Count: count})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Id < items[j].Id })
+	writeJSON(w, map[string]any{"result": true, "items": items})
+}
+
+func (s *Server) mergeTags(w http.ResponseWriter, r *http.Request, collectionId int64) {
+	var in TagsBody
+	if !readJSON(w, r, &in) {
+		return
+	}
+	s.rewriteTags(collectionId, in.Tags, in.Replace)
+	writeJSON(w, map[string]any{"result": true})
+}
+
+func (s *Server) removeTags(w http.ResponseWriter, r *http.Request, collectionId int64) {
+	var in TagsBody
+	if !readJSON(w, r, &in) {
+		return
+	}
+	s.rewriteTags(collectionId, in.Tags, "")
+	writeJSON(w, map[string]any{"result": true})
+}
+
+// Replace tags with replace, or drop them when replace is empty
+func (s *Server) rewriteTags(collectionId int64, tags []string, replace string) {
+	for _, entry := range s.match(collectionId, "", nil) {
+		var kept []string
+		found := false
+		for _, tag := range entry.data.Tags {
+			if contains(tags, tag) {
+				found = true
+				continue
+			}
+			kept = append(kept, tag)
+		}
+		if found && replace != "" && !contains(kept, replace) {
+			kept = append(kept, replace)
+		}
+		entry.data.Tags = kept
+	}
+}
+
+// ------------------------------------------------------------------------
+// Import
+// ------------------------------------------------------------------------
+
+func (s *Server) urlsExist(w http.ResponseWriter, r *http.Request) {
+	var in URLsBody
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	ids := []int64{}
+	for _, id := range sortedKeys(s.raindrops) {
+		if contains(in.Urls, s.raindrops[id].data.Link) {
+			ids = append(ids, id)
+		}
+	}
+	writeJSON(w, map[string]any{"result": true, "ids": ids})
+}
+
+// Parse a bookmark file into a single folder named after it. Only the
+// links of the netscape format are read; nothing is imported.
+func (s *Server) importFile(w http.ResponseWriter, r *http.Request) {
+	upload, ok := readUpload(w, r, "import")
+	if !ok {
+		return
+	}
+
+	folder := ImportFolderType{Title: upload.name}
+	for _, match := range bookmarkLink.FindAllStringSubmatch(string(upload.content), -1) {
+		folder.Bookmarks = append(folder.Bookmarks, RaindropType{Link: html.UnescapeString(match[1]), Title: html.UnescapeString(match[2])})
+	}
+	writeJSON(w, map[string]any{"result": true, "items": []ImportFolderType{folder}})
+}
+
+var bookmarkLink = regexp.MustCompile(`(?i)<A HREF="([^"]*)"[^>]*>([^<]*)</A>`)
+
+// ------------------------------------------------------------------------
+// Exports and backups
+// ------------------------------------------------------------------------
+
+func (s *Server) exportRaindrops(w http.ResponseWriter, r *http.Request, collectionId int64, format string) {
+	matched := s.match(collectionId, r.URL.Query().Get("search"), nil)
+	sortRaindrops(matched, r.URL.Query().Get("sort"))
+
+	switch format {
+	case EXPORT_CSV, EXPORT_HTML:
+		writeExport(w, format, matched)
+	case EXPORT_ZIP:
+		w.Header().Set("Content-Type", "application/zip")
+		archive := zip.NewWriter(w)
+		file, _ := archive.Create("export.csv")
+		writeCSV(file, matched)
+		archive.Close()
+	default:
+		writeError(w, http.StatusBadRequest, "bad_request", "unknown export format "+format)
+	}
+}
+
+// Backups are generated on the spot and hold every raindrop
+func (s *Server) generateBackup(w http.ResponseWriter) {
+	s.nextId++
+	s.backups = append(s.backups, BackupType{Id: strconv.FormatInt(s.nextId, 10), Created: now()})
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, "We will send you an email with a link to the backup")
+}
+
+func (s *Server) downloadBackup(w http.ResponseWriter, file string) {
+	id, format, _ := strings.Cut(file, ".")
+	found := false
+	for _, backup := range s.backups {
+		found = found || backup.Id == id
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "not_found", "backup not found")
+		return
+	}
+	if format != EXPORT_CSV && format != EXPORT_HTML {
+		writeError(w, http.StatusBadRequest, "bad_request", "unknown backup format "+format)
+		return
+	}
+
+	matched := s.match(int64(COLLECTION_ALL), "", nil)
+	sortRaindrops(matched, "created")
+	writeExport(w, format, matched)
+}
+
+func writeExport(w http.ResponseWriter, format string, entries []*raindropEntry) {
+	if format == EXPORT_CSV {
+		w.Header().Set("Content-Type", "text/csv")
+		writeCSV(w, entries)
+		return
+	}
+
+	// the netscape bookmark format browsers import
+	w.Header().Set("Content-Type", "text/html")
+	fmt.Fprint(w, "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n<DL><p>\n")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "<DT><A HREF=\"%s\" TAGS=\"%s\">%s</A>\n",
+			html.EscapeString(entry.data.Link), html.EscapeString(strings.Join(entry.data.Tags, ",")), html.EscapeString(entry.data.Title))
+	}
+	fmt.Fprint(w, "</DL><p>\n")
+}
+
+func writeCSV(w io.Writer, entries []*raindropEntry) {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "title", "url", "tags", "created"})
+	for _, entry := range entries {
+		out.Write([]string{strconv.FormatInt(entry.id, 10), entry.data.Title, entry.data.Link, strings.Join(entry.data.Tags, ", "), entry.data.Created})
+	}
+	out.Flush()
+}
+
+// ------------------------------------------------------------------------
+// Filtering
+// ------------------------------------------------------------------------
+
+// Raindrops of a collection matching a search and, when not empty, ids.
+// Collection 0 is every collection but the trash.
+func (s *Server) match(collectionId int64, search string, ids []int) []*raindropEntry {
+	terms := splitTerms(search)
+
+	var out []*raindropEntry
+	for _, id := range sortedKeys(s.raindrops) {
+		entry := s.raindrops[id]
+		if collectionId == int64(COLLECTION_ALL) && entry.collection == int64(COLLECTION_TRASH) {
+			continue
+		}
+		if collectionId != int64(COLLECTION_ALL) && entry.collection != collectionId {
+			continue
+		}
+		if len(ids) > 0 && !containsId(ids, entry.id) {
+			continue
+		}
+		if !matchesAll(entry, terms) {
+			continue
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// Split a search on spaces, keeping quoted values together
+func splitTerms(search string) []string {
+	var terms []string
+	var current strings.Builder
+	quoted := false
+
+	for _, c := range search {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			current.WriteRune(c)
+		case (c == ' ' || c == '\t') && !quoted:
+			if current.Len() > 0 {
+				terms = append(terms, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(c)
+		}
+	}
+	if current.Len() > 0 {
+		terms = append(terms, current.String())
+	}
+	return terms
+}
+
+// Supports #tag, type:, important:, notag:, domain:, created: (with > or
+// < and a yyyy-mm-dd date) and free text or "phrases" over title,
+// excerpt, note and link. A leading - negates a term.
+func matchesAll(entry *raindropEntry, terms []string) bool {
+	for _, term := range terms {
+		negate := strings.HasPrefix(term, "-") && len(term) > 1
+		if negate {
+			term = term[1:]
+		}
+		if matchesTerm(entry, term) == negate {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTerm(entry *raindropEntry, term string) bool {
+	data := entry.data
+
+	if strings.HasPrefix(term, "#") {
+		return contains(data.Tags, strings.Trim(term[1:], `"`))
+	}
+	if key, value, ok := strings.Cut(term, ":"); ok && !strings.HasPrefix(term, `"`) {
+		value = strings.Trim(value, `"`)
+		switch key {
+		case "type":
+			return data.Type == value
+		case "important":
+			return data.Important == (value == "true")
+		case "notag":
+			return (len(data.Tags) == 0) == (value == "true")
+		case "domain":
+			return data.Domain == value || strings.HasSuffix(data.Domain, "."+value)
+		case "created":
+			return matchesDate(data.Created, value)
+		}
+	}
+
+	term = strings.ToLower(strings.Trim(term, `"`))
+	for _, field := range []string{data.Title, data.Excerpt, data.Note, data.Link} {
+		if strings.Contains(strings.ToLower(field), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare the day of an RFC 3339 timestamp with ">yyyy-mm-dd",
+// "<yyyy-mm-dd" or "yyyy-mm-dd"
+func matchesDate(created string, value string) bool {
+	if len(created) < len(SEARCH_DATE_LAYOUT) {
+		return false
+	}
+	day := created[:len(SEARCH_DATE_LAYOUT)]
+
+	switch {
+	case strings.HasPrefix(value, ">"):
+		return day > value[1:]
+	case strings.HasPrefix(value, "<"):
+		return day < value[1:]
+	default:
+		return day == value
+	}
+}
+
+// Sort by created (default newest first) or title
+func sortRaindrops(entries []*raindropEntry, by string) {
+	if by == "" {
+		by = "-created"
+	}
+	desc := strings.HasPrefix(by, "-")
+	field := strings.TrimPrefix(by, "-")
+
+	less := func(a, b *raindropEntry) bool {
+		if field == "title" && a.data.Title != b.data.Title {
+			return a.data.Title < b.data.Title
+		}
+		if field != "title" && a.data.Created != b.data.Created {
+			return a.data.Created < b.data.Created
+		}
+		return a.id < b.id
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if desc {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+}
+
+func sortedKeys[T any](m map[int64]T) []int64 {
+	keys := make([]int64, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsId(list []int, id int64) bool {
+	for _, item := range list {
+		if int64(item) == id {
+			return true
+		}
+	}
+	return false
+}